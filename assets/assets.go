@@ -0,0 +1,8 @@
+package assets
+
+import "embed"
+
+// Runner holds the build context of the runner image
+//
+//go:embed runner
+var Runner embed.FS
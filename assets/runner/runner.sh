@@ -1,4 +1,22 @@
 #!/bin/bash
 
-./config.sh --url $GITHUB_RUNNER_REPOSITORY --token $GITHUB_RUNNER_TOKEN --labels="${GITHUB_RUNNER_LABELS}"
-./run.sh
\ No newline at end of file
+# just-in-time runners were registered through the api, the encoded config is all run.sh needs
+if [ -n "$GITHUB_RUNNER_JITCONFIG" ]; then
+  exec ./run.sh --jitconfig "$GITHUB_RUNNER_JITCONFIG"
+fi
+
+# ephemeral runners take exactly one job and unregister themselves afterwards, the container is thrown away with the
+# workspace once run.sh exits
+./config.sh \
+  --url "$GITHUB_RUNNER_REPOSITORY" \
+  --token "$GITHUB_RUNNER_TOKEN" \
+  --labels "${GITHUB_RUNNER_LABELS}" \
+  --name "${GITHUB_RUNNER_NAME:-$(hostname)}" \
+  --runnergroup "${GITHUB_RUNNER_GROUP:-Default}" \
+  --work _work \
+  --ephemeral \
+  --disableupdate \
+  --unattended \
+  --replace || exit 1
+
+exec ./run.sh
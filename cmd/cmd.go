@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"mirasynth.stream/github-runner/internal/config"
-	"mirasynth.stream/github-runner/internal/container"
 	"mirasynth.stream/github-runner/internal/github"
 	"os"
 
@@ -31,25 +30,6 @@ func init() {
 				return err
 			}
 
-			containerClient, err := container.New()
-			containerId, err := containerClient.Create(cmd.Context(), &container.Options{
-				Name:      "runner-test",
-				ImageName: "miras-github-runner:alpha",
-				Environment: []string{
-					"GITHUB_RUNNER_REPOSITORY", "",
-					"GITHUB_RUNNER_TOKEN", "",
-					"GITHUB_RUNNER_LABELS", "",
-				},
-			})
-			if err != nil {
-				return err
-			}
-
-			err = containerClient.Start(cmd.Context(), containerId)
-			if err != nil {
-				return err
-			}
-
 			gc, err := github.GetClient(cmd.Context(), nil)
 			if err != nil {
 				return err
@@ -64,6 +44,9 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&configFilePath, "config", "", "Sets the path to where the config file is loaded from")
 
 	rootCmd.AddCommand(NewServerCmd())
+	rootCmd.AddCommand(NewProxyCmd())
+	rootCmd.AddCommand(NewImageCmd())
+	rootCmd.AddCommand(NewRunnerGroupsCmd())
 }
 
 func Execute() {
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"mirasynth.stream/github-runner/internal/atlas"
+	"mirasynth.stream/github-runner/internal/config"
+	"mirasynth.stream/github-runner/internal/container"
+	"mirasynth.stream/github-runner/internal/runnerimage"
+)
+
+func NewImageCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "image",
+		Short: atlas.IMAGE_COMMAND_SHORT_DESC,
+		Long:  atlas.IMAGE_COMMAND_LONG_DESC,
+		// building an image only needs the config and docker, not a GitHub client
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			log.SetFormatter(&log.JSONFormatter{})
+			log.SetOutput(os.Stdout)
+			log.SetLevel(log.DebugLevel)
+
+			return config.SetupConfig(configFilePath)
+		},
+	}
+
+	cmd.AddCommand(newImageBuildCmd())
+
+	return cmd
+}
+
+func newImageBuildCmd() *cobra.Command {
+	var version string
+	var checksum string
+	var tags []string
+
+	cmd := &cobra.Command{
+		Use:   "build",
+		Short: atlas.IMAGE_BUILD_COMMAND_SHORT_DESC,
+		Long:  atlas.IMAGE_BUILD_COMMAND_LONG_DESC,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// an overridden version comes with its own checksum, the configured one belongs to the configured version
+			if version == "" {
+				version = config.Get().Runner.Version
+				if checksum == "" {
+					checksum = config.Get().Runner.Checksum
+				}
+			}
+
+			auths, err := getRegistryAuths()
+			if err != nil {
+				return err
+			}
+
+			containerClient, err := container.New(auths)
+			if err != nil {
+				return err
+			}
+			defer containerClient.Close()
+
+			return runnerimage.Build(cmd.Context(), containerClient, &runnerimage.BuildOptions{
+				Version:  version,
+				Checksum: checksum,
+				Tags:     tags,
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&version, "version", "", "Sets the version of the actions runner, defaults to runner.version from the config")
+	cmd.Flags().StringVar(&checksum, "checksum", "", "Sets the sha256 of the runner release archive, required along with --version")
+	cmd.Flags().StringSliceVar(&tags, "tag", []string{atlas.DEFAULT_RUNNER_IMAGE}, "Sets the tags the image gets on top of its version tag")
+	cmd.MarkFlagsRequiredTogether("version", "checksum")
+
+	return cmd
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"mirasynth.stream/github-runner/internal/atlas"
+	"mirasynth.stream/github-runner/internal/proxy"
+)
+
+func NewProxyCmd() *cobra.Command {
+	var listen string
+	var allow []string
+
+	cmd := &cobra.Command{
+		Use:   "proxy",
+		Short: atlas.PROXY_COMMAND_SHORT_DESC,
+		Long:  atlas.PROXY_COMMAND_LONG_DESC,
+		// the proxy runs as a sidecar next to a runner, without a config file or any access to GitHub
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			log.SetFormatter(&log.JSONFormatter{})
+			log.SetOutput(os.Stdout)
+			log.SetLevel(log.InfoLevel)
+
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(allow) == 0 && os.Getenv(atlas.EGRESS_ALLOW_ENV) != "" {
+				allow = strings.Split(os.Getenv(atlas.EGRESS_ALLOW_ENV), ",")
+			}
+
+			if len(allow) == 0 {
+				return fmt.Errorf("the allow list is empty, pass --allow or set %s", atlas.EGRESS_ALLOW_ENV)
+			}
+
+			log.WithFields(log.Fields{
+				"listen": listen,
+				"allow":  allow,
+			}).Info("starting egress proxy")
+
+			return http.ListenAndServe(listen, proxy.New(allow))
+		},
+	}
+
+	cmd.Flags().StringVar(&listen, "listen", fmt.Sprintf(":%d", atlas.EGRESS_PROXY_PORT), "Sets the address the proxy listens on")
+	cmd.Flags().StringSliceVar(&allow, "allow", nil, "Sets the hosts the proxy lets requests through to, *.example.com matches subdomains")
+
+	return cmd
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"mirasynth.stream/github-runner/internal/atlas"
+	"mirasynth.stream/github-runner/internal/config"
+	"mirasynth.stream/github-runner/internal/runnergroup"
+)
+
+func NewRunnerGroupsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "runner-groups",
+		Short: atlas.RUNNER_GROUPS_COMMAND_SHORT_DESC,
+		Long:  atlas.RUNNER_GROUPS_COMMAND_LONG_DESC,
+	}
+
+	cmd.AddCommand(newRunnerGroupsSyncCmd())
+
+	return cmd
+}
+
+func newRunnerGroupsSyncCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "sync",
+		Short: atlas.RUNNER_GROUPS_SYNC_COMMAND_SHORT_DESC,
+		Long:  atlas.RUNNER_GROUPS_SYNC_COMMAND_LONG_DESC,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runnergroup.Sync(*githubClient, config.Get().RunnerGroups)
+		},
+	}
+}
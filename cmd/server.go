@@ -1,20 +1,148 @@
 package cmd
 
 import (
+	"fmt"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"mirasynth.stream/github-runner/internal/atlas"
+	"mirasynth.stream/github-runner/internal/collector"
+	"mirasynth.stream/github-runner/internal/config"
+	"mirasynth.stream/github-runner/internal/container"
+	"mirasynth.stream/github-runner/internal/dispatcher"
+	"mirasynth.stream/github-runner/internal/poller"
+	"mirasynth.stream/github-runner/internal/reconciler"
+	"mirasynth.stream/github-runner/internal/recovery"
+	"mirasynth.stream/github-runner/internal/runnerimage"
 	"mirasynth.stream/github-runner/internal/server"
+	"mirasynth.stream/github-runner/internal/store"
 )
 
+const instanceIdKey = "daemon:instance"
+
 func NewServerCmd() *cobra.Command {
+	var mode string
+
 	cmd := &cobra.Command{
 		Use:   "server",
 		Short: atlas.SERVER_COMMAND_SHORT_DESC,
 		Long:  atlas.SERVER_COMMAND_LONG_DESC,
-		Run: func(cmd *cobra.Command, args []string) {
-			server.StartServer()
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if mode != atlas.SERVER_MODE_WEBHOOK && mode != atlas.SERVER_MODE_POLL {
+				return fmt.Errorf("unknown mode %q, expected %s or %s", mode, atlas.SERVER_MODE_WEBHOOK, atlas.SERVER_MODE_POLL)
+			}
+
+			if mode == atlas.SERVER_MODE_WEBHOOK {
+				err := config.ValidateWebhook()
+				if err != nil {
+					return err
+				}
+			}
+
+			auths, err := getRegistryAuths()
+			if err != nil {
+				return err
+			}
+
+			containerClient, err := container.New(auths)
+			if err != nil {
+				return err
+			}
+			defer containerClient.Close()
+
+			storePath, err := config.GetStorePath()
+			if err != nil {
+				return err
+			}
+
+			s, err := store.New(storePath)
+			if err != nil {
+				return err
+			}
+
+			instanceId, err := getInstanceId(s)
+			if err != nil {
+				return err
+			}
+
+			d := dispatcher.New(*githubClient, containerClient, s, instanceId)
+
+			err = d.Adopt(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			go func() {
+				if config.Get().Runner.Build {
+					err := runnerimage.EnsureBuilt(cmd.Context(), containerClient)
+					if err != nil {
+						log.Error(err)
+					}
+				}
+
+				d.Prepull(cmd.Context())
+			}()
+
+			go reconciler.New(*githubClient, d, s).Run(cmd.Context())
+
+			if config.Get().Collector.Enabled {
+				go collector.New(*githubClient, containerClient, d, s, instanceId).Run(cmd.Context())
+			}
+
+			switch mode {
+			case atlas.SERVER_MODE_POLL:
+				go poller.New(*githubClient, d).Run(cmd.Context())
+			case atlas.SERVER_MODE_WEBHOOK:
+				if config.Get().GitHub.Webhook.Recovery.Enabled {
+					go recovery.New(*githubClient, d, s).Run(cmd.Context())
+				}
+			}
+
+			server.StartServer(d)
+
+			return nil
 		},
 	}
 
+	cmd.Flags().StringVar(&mode, "mode", atlas.SERVER_MODE_WEBHOOK, "Sets how queued jobs are found, either webhook or poll")
+
 	return cmd
 }
+
+// getInstanceId returns the configured instance id, or the one generated by an earlier run of the daemon, so its
+// containers can be adopted after a restart
+func getInstanceId(s store.Store) (string, error) {
+	if config.Get().InstanceId != "" {
+		return config.Get().InstanceId, nil
+	}
+
+	instanceId, ok := s.Get(instanceIdKey)
+	if ok {
+		return instanceId, nil
+	}
+
+	instanceId = uuid.NewString()
+	return instanceId, s.Set(instanceIdKey, instanceId, 0)
+}
+
+// getRegistryAuths returns the registry credentials from the config, which take precedence over the ones from the
+// docker config.json
+func getRegistryAuths() ([]container.RegistryAuth, error) {
+	var auths []container.RegistryAuth
+	for _, registry := range config.Get().Registries {
+		auths = append(auths, container.RegistryAuth{
+			Server:        registry.Server,
+			Username:      registry.Username,
+			Password:      registry.Password,
+			IdentityToken: registry.IdentityToken,
+		})
+	}
+
+	dockerConfigAuths, err := container.LoadDockerConfig(config.Get().DockerConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(auths, dockerConfigAuths...), nil
+}
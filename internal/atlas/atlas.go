@@ -4,9 +4,56 @@ const CONFIG_NAMESPACE = "mirasynth"
 const CONFIG_PREFIX = "github-runner"
 const CONFIG_TYPE = "yaml"
 const CONFIG_FILENAME = "config"
+const STORE_FILENAME = "state.json"
 
 const GITHUBRUNNER_SHORT_DESC = "A collection of commands that manages github runners on your repositories"
 const GITHUBRUNNER_LONG_DESC = "A collection of commands that manages github runners on your repositories"
 
 const SERVER_COMMAND_SHORT_DESC = "Starts a webhook server to revieve notifications"
 const SERVER_COMMAND_LONG_DESC = "Starts a webhook server to revieve notifications"
+
+const IMAGE_COMMAND_SHORT_DESC = "A collection of commands that manages the runner image"
+const IMAGE_COMMAND_LONG_DESC = "A collection of commands that manages the runner image"
+
+const IMAGE_BUILD_COMMAND_SHORT_DESC = "Builds the runner image from the embedded assets"
+const IMAGE_BUILD_COMMAND_LONG_DESC = "Builds the runner image from the embedded assets and tags it with the runner version"
+
+const RUNNER_GROUPS_COMMAND_SHORT_DESC = "A collection of commands that manages the runner groups of your organizations"
+const RUNNER_GROUPS_COMMAND_LONG_DESC = "A collection of commands that manages the runner groups of your organizations"
+
+const RUNNER_GROUPS_SYNC_COMMAND_SHORT_DESC = "Syncs the runner groups declared in the config to GitHub"
+const RUNNER_GROUPS_SYNC_COMMAND_LONG_DESC = "Creates the runner groups declared in the config that are missing and brings the visibility and repository access of every group in line with the config"
+
+const PROXY_COMMAND_SHORT_DESC = "Starts an http proxy that only lets requests through to allowed hosts"
+const PROXY_COMMAND_LONG_DESC = "Starts an http proxy that only lets requests through to allowed hosts, runners use it as an egress sidecar"
+
+const DEFAULT_GITHUB_API_URL = "https://api.github.com"
+const DEFAULT_GITHUB_WEB_URL = "https://github.com"
+
+const SERVER_MODE_WEBHOOK = "webhook"
+const SERVER_MODE_POLL = "poll"
+
+const RUNNER_IMAGE_REPOSITORY = "miras-github-runner"
+const DEFAULT_RUNNER_IMAGE = "miras-github-runner:alpha"
+const DEFAULT_RUNNER_VERSION = "2.316.1"
+const DEFAULT_RUNNER_CHECKSUM = "d62de2400eeeacd195db91e2ff011bfb646cd5d85545e81d8f78c436183e09a8"
+const RUNNER_HOME = "/home/nonroot"
+const DEFAULT_PROXY_IMAGE = "miras-github-runner-server:alpha"
+
+const EGRESS_PROXY_PORT = 3128
+const EGRESS_PROXY_ALIAS = "egress-proxy"
+const EGRESS_ALLOW_ENV = "GITHUB_RUNNER_EGRESS_ALLOW"
+
+const CONTAINER_LABEL_INSTANCE = "stream.mirasynth.github-runner.instance"
+const CONTAINER_LABEL_POOL = "stream.mirasynth.github-runner.pool"
+const CONTAINER_LABEL_REPOSITORY = "stream.mirasynth.github-runner.repository"
+const CONTAINER_LABEL_SCOPE = "stream.mirasynth.github-runner.scope"
+const CONTAINER_LABEL_JOB = "stream.mirasynth.github-runner.job"
+const CONTAINER_LABEL_RUNNER = "stream.mirasynth.github-runner.runner"
+const CONTAINER_LABEL_SIDECAR = "stream.mirasynth.github-runner.sidecar"
+
+const VOLUME_LABEL_CACHE = "stream.mirasynth.github-runner.cache"
+const VOLUME_LABEL_SNAPSHOT = "stream.mirasynth.github-runner.snapshot"
+const VOLUME_LABEL_LOWER = "stream.mirasynth.github-runner.lower"
+
+const IMAGE_LABEL_RUNNER_VERSION = "stream.mirasynth.github-runner.version"
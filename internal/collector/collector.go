@@ -0,0 +1,463 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/docker/go-units"
+	log "github.com/sirupsen/logrus"
+	"mirasynth.stream/github-runner/internal/atlas"
+	"mirasynth.stream/github-runner/internal/config"
+	"mirasynth.stream/github-runner/internal/container"
+	"mirasynth.stream/github-runner/internal/dispatcher"
+	"mirasynth.stream/github-runner/internal/github"
+	"mirasynth.stream/github-runner/internal/metrics"
+	"mirasynth.stream/github-runner/internal/runnerimage"
+	"mirasynth.stream/github-runner/internal/store"
+)
+
+const stopTimeout = 30 * time.Second
+
+// Collector removes the runner containers, sidecars, networks, volumes and runner registrations that lost their
+// counterpart, and evicts caches that grew too large
+type Collector interface {
+	Collect(context.Context) error
+	// Run collects on the configured interval until the context is cancelled
+	Run(context.Context)
+}
+
+type implementation struct {
+	github     github.Client
+	container  container.Container
+	dispatcher dispatcher.Dispatcher
+	store      store.Store
+	instanceId string
+}
+
+func New(githubClient github.Client, containerClient container.Container, d dispatcher.Dispatcher, s store.Store, instanceId string) Collector {
+	return &implementation{
+		github:     githubClient,
+		container:  containerClient,
+		dispatcher: d,
+		store:      s,
+		instanceId: instanceId,
+	}
+}
+
+func (c *implementation) Run(ctx context.Context) {
+	ticker := time.NewTicker(config.Get().Collector.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := c.Collect(ctx)
+		if err != nil {
+			metrics.CollectorErrors.Add(1)
+			log.WithField("component", "collector").Error(err)
+		}
+	}
+}
+
+// Collect makes a single pass over the containers of the instance and the runners registered on the repositories
+// of the installation, and on their organizations when a pool is organization scoped
+func (c *implementation) Collect(ctx context.Context) error {
+	metrics.CollectorRuns.Add(1)
+	metrics.CollectorLastRun.Set(time.Now().Unix())
+
+	infos, err := c.container.List(ctx, map[string]string{
+		atlas.CONTAINER_LABEL_INSTANCE: c.instanceId,
+	})
+	if err != nil {
+		return err
+	}
+
+	repositories, err := c.github.ListRepositoriesForAppInstallation(&github.ListRepositoriesForAppInstallationOptions{})
+	if err != nil {
+		return err
+	}
+
+	// every repository of the installation is listed, runners whose container is long gone leave no other trace
+	registrations := map[string]dispatcher.Registration{}
+	for _, repository := range repositories.Repositories {
+		registration := dispatcher.NewRepositoryRegistration(repository.Owner.Login, repository.Name)
+		registrations[registration.String()] = registration
+
+		if repository.Owner.Type == "Organization" && hasOrganizationPool() {
+			registration = dispatcher.NewOrganizationRegistration(repository.Owner.Login)
+			registrations[registration.String()] = registration
+		}
+	}
+
+	runnersByRegistration := map[string][]github.Runner{}
+	for key, registration := range registrations {
+		runners, err := registration.ListRunners(c.github)
+		if err != nil {
+			return err
+		}
+
+		runnersByRegistration[key] = runners
+	}
+
+	containersByRunner := map[string]*container.Info{}
+	var sidecars []container.Info
+	var errs []error
+	for i := range infos {
+		info := &infos[i]
+		if info.Labels[atlas.CONTAINER_LABEL_SIDECAR] != "" {
+			sidecars = append(sidecars, *info)
+			continue
+		}
+
+		containersByRunner[info.Labels[atlas.CONTAINER_LABEL_RUNNER]] = info
+
+		err = c.collectContainer(ctx, info, runnersByRegistration)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	err = c.collectNetworks(ctx, sidecars, containersByRunner)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	err = c.collectVolumes(ctx, containersByRunner)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	err = c.checkVersions(ctx, containersByRunner)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	// runners dispatched since the containers were listed are registered already, but missing from that list
+	activeRunners, err := c.activeRunners(ctx)
+	if err != nil {
+		errs = append(errs, err)
+	} else {
+		for key, runners := range runnersByRegistration {
+			err = c.collectRunners(registrations[key], runners, activeRunners)
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"component":    "collector",
+		"containers":   len(infos),
+		"repositories": len(repositories.Repositories),
+		"errors":       len(errs),
+	}).Info("collector run finished")
+
+	return errors.Join(errs...)
+}
+
+// collectContainer removes a container that exited, outlived the maximum runner age or whose runner is no longer
+// registered on GitHub
+func (c *implementation) collectContainer(ctx context.Context, info *container.Info, runnersByRegistration map[string][]github.Runner) error {
+	collectorConfig := config.Get().Collector
+	runnerName := info.Labels[atlas.CONTAINER_LABEL_RUNNER]
+	repository := info.Labels[atlas.CONTAINER_LABEL_REPOSITORY]
+	registration := dispatcher.NewRunnerFromContainer(info).Registration()
+	age := time.Since(info.CreatedAt)
+
+	reason := ""
+	switch {
+	case !info.Running:
+		reason = "container has exited"
+	case age > collectorConfig.MaxRunnerAge:
+		reason = "container is older than the maximum runner age"
+	case age > collectorConfig.RegistrationGracePeriod && !hasRunner(runnersByRegistration[registration.String()], runnerName):
+		reason = "runner is no longer registered on GitHub"
+	}
+
+	if reason == "" {
+		return nil
+	}
+
+	log.WithFields(log.Fields{
+		"component":  "collector",
+		"container":  info.Id,
+		"runner":     runnerName,
+		"repository": repository,
+	}).Info(fmt.Sprintf("removing runner container, %s", reason))
+
+	if info.Running {
+		err := c.container.Stop(ctx, info.Id, stopTimeout)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := c.container.Remove(ctx, info.Id)
+	if err != nil {
+		return err
+	}
+
+	info.Running = false
+	metrics.CollectorContainersRemoved.Add(1)
+	return nil
+}
+
+// collectNetworks removes the sidecars and networks of runners whose container is gone
+func (c *implementation) collectNetworks(ctx context.Context, sidecars []container.Info, containersByRunner map[string]*container.Info) error {
+	var errs []error
+	for _, sidecar := range sidecars {
+		runnerName := sidecar.Labels[atlas.CONTAINER_LABEL_RUNNER]
+		if !isOrphaned(containersByRunner, runnerName, sidecar.CreatedAt) {
+			continue
+		}
+
+		log.WithFields(log.Fields{
+			"component": "collector",
+			"container": sidecar.Id,
+			"runner":    runnerName,
+		}).Info("removing sidecar container of a runner that is gone")
+
+		err := c.container.Remove(ctx, sidecar.Id)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		metrics.CollectorContainersRemoved.Add(1)
+	}
+
+	networks, err := c.container.ListNetworks(ctx, map[string]string{
+		atlas.CONTAINER_LABEL_INSTANCE: c.instanceId,
+	})
+	if err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+
+	for _, network := range networks {
+		runnerName := network.Labels[atlas.CONTAINER_LABEL_RUNNER]
+		if !isOrphaned(containersByRunner, runnerName, network.CreatedAt) {
+			continue
+		}
+
+		log.WithFields(log.Fields{
+			"component": "collector",
+			"network":   network.Name,
+			"runner":    runnerName,
+		}).Info("removing network of a runner that is gone")
+
+		err = c.container.RemoveNetwork(ctx, network.Id)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// activeRunners returns the names of the runners that have a container or that the dispatcher is still starting
+func (c *implementation) activeRunners(ctx context.Context) (map[string]bool, error) {
+	infos, err := c.container.List(ctx, map[string]string{
+		atlas.CONTAINER_LABEL_INSTANCE: c.instanceId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	active := map[string]bool{}
+	for _, info := range infos {
+		active[info.Labels[atlas.CONTAINER_LABEL_RUNNER]] = true
+	}
+
+	for _, r := range c.dispatcher.Runners() {
+		active[r.Name] = true
+	}
+
+	return active, nil
+}
+
+// collectRunners deregisters the offline runners that were started by this instance but have no container anymore.
+// Runners this instance has no record of belong to another instance and are left alone, and a runner gets the
+// registration grace period to start its container.
+func (c *implementation) collectRunners(registration dispatcher.Registration, runners []github.Runner, activeRunners map[string]bool) error {
+	var errs []error
+	for _, runner := range runners {
+		if runner.Status != "offline" || activeRunners[runner.Name] || !dispatcher.IsRunnerName(runner.Name) {
+			continue
+		}
+
+		recordedAt, ok := c.store.Get(dispatcher.RunnerKey(runner.Name))
+		if !ok {
+			continue
+		}
+
+		createdAt, err := time.Parse(time.RFC3339, recordedAt)
+		if err == nil && time.Since(createdAt) < config.Get().Collector.RegistrationGracePeriod {
+			continue
+		}
+
+		log.WithFields(log.Fields{
+			"component":    "collector",
+			"runner":       runner.Name,
+			"registration": registration.String(),
+		}).Info("deregistering offline runner without a container")
+
+		err = registration.DeleteRunner(c.github, runner.Id)
+		if err == nil {
+			err = c.store.Release(dispatcher.RunnerKey(runner.Name))
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		metrics.CollectorRunnersDeregistered.Add(1)
+	}
+
+	return errors.Join(errs...)
+}
+
+// collectVolumes removes the snapshots of runners that are gone and evicts the caches that grew beyond their maximum
+// size. A cache in use by a runner, mounted or as the lower dir of a snapshot, is left alone until the next run.
+func (c *implementation) collectVolumes(ctx context.Context, containersByRunner map[string]*container.Info) error {
+	volumes, err := c.container.ListVolumes(ctx, map[string]string{
+		atlas.CONTAINER_LABEL_INSTANCE: c.instanceId,
+	})
+	if err != nil {
+		return err
+	}
+
+	// docker does not count an overlay on a cache as a reference, so the snapshots that are still around tell which
+	// caches copy on write runners are using
+	overlaid := map[string]bool{}
+	for _, v := range volumes {
+		if v.Labels[atlas.VOLUME_LABEL_SNAPSHOT] != "" {
+			overlaid[v.Labels[atlas.VOLUME_LABEL_LOWER]] = true
+		}
+	}
+
+	var errs []error
+	for _, v := range volumes {
+		logger := log.WithFields(log.Fields{
+			"component": "collector",
+			"volume":    v.Name,
+		})
+
+		if v.Labels[atlas.VOLUME_LABEL_SNAPSHOT] != "" {
+			runnerName := v.Labels[atlas.CONTAINER_LABEL_RUNNER]
+			if !isOrphaned(containersByRunner, runnerName, v.CreatedAt) {
+				continue
+			}
+
+			logger.WithField("runner", runnerName).Info("removing snapshot volume of a runner that is gone")
+		} else {
+			maxSize, ok := cacheMaxSize(&v)
+			if !ok || v.Size <= maxSize {
+				continue
+			}
+
+			if v.RefCount > 0 || overlaid[v.Name] {
+				logger.Debug("cache volume is over its maximum size but still in use")
+				continue
+			}
+
+			logger.WithFields(log.Fields{
+				"size":    units.BytesSize(float64(v.Size)),
+				"maxSize": units.BytesSize(float64(maxSize)),
+			}).Info("evicting cache volume that grew beyond its maximum size")
+		}
+
+		err = c.container.RemoveVolume(ctx, v.Name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		metrics.CollectorVolumesRemoved.Add(1)
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkVersions counts the running runners whose image is older than the minimum runner version
+func (c *implementation) checkVersions(ctx context.Context, containersByRunner map[string]*container.Info) error {
+	outdatedImages := map[string]bool{}
+	outdatedRunners := int64(0)
+
+	for _, info := range containersByRunner {
+		if !info.Running {
+			continue
+		}
+
+		outdated, checked := outdatedImages[info.ImageName]
+		if !checked {
+			var err error
+			outdated, err = runnerimage.CheckVersion(ctx, c.container, info.ImageName)
+			if err != nil {
+				return err
+			}
+			outdatedImages[info.ImageName] = outdated
+		}
+
+		if outdated {
+			outdatedRunners++
+		}
+	}
+
+	metrics.RunnersOutdated.Set(outdatedRunners)
+	return nil
+}
+
+// cacheMaxSize looks up the maximum size of a cache volume in the config of its pool
+func cacheMaxSize(v *container.VolumeInfo) (int64, bool) {
+	pool := config.Get().GetPool(v.Labels[atlas.CONTAINER_LABEL_POOL])
+	if pool == nil {
+		return 0, false
+	}
+
+	cache := pool.GetCache(v.Labels[atlas.VOLUME_LABEL_CACHE])
+	if cache == nil || cache.MaxSize == "" {
+		return 0, false
+	}
+
+	maxSize, err := units.RAMInBytes(cache.MaxSize)
+	if err != nil {
+		return 0, false
+	}
+
+	return maxSize, true
+}
+
+// isOrphaned reports whether whatever belongs to the runner outlived its container. It is created before the runner
+// container, so it gets the registration grace period to not be pulled out from under a starting runner.
+func isOrphaned(containersByRunner map[string]*container.Info, runnerName string, createdAt time.Time) bool {
+	info, hasContainer := containersByRunner[runnerName]
+	return (!hasContainer || !info.Running) && time.Since(createdAt) > config.Get().Collector.RegistrationGracePeriod
+}
+
+// hasOrganizationPool reports whether any pool registers its runners with organizations, only then are the runners of
+// the organizations listed, which needs the organization self-hosted runners permission
+func hasOrganizationPool() bool {
+	for _, pool := range config.Get().Pools {
+		if pool.Scope == config.PoolScopeOrganization {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasRunner(runners []github.Runner, name string) bool {
+	for _, runner := range runners {
+		if runner.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -5,21 +5,129 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"slices"
 	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
 	"mirasynth.stream/github-runner/internal/atlas"
 )
 
 var venv *viper.Viper
+var instance *Config
+
+type Recovery struct {
+	Enabled  bool          `json:"enabled"`
+	Interval time.Duration `json:"interval"`
+	// Lookback is how far back missed deliveries are replayed from
+	Lookback time.Duration `json:"lookback"`
+	// Redeliver asks GitHub to redeliver failed deliveries instead of replaying them in process
+	Redeliver bool `json:"redeliver"`
+}
+
+type Webhook struct {
+	Secret           string        `json:"secret"`
+	Secrets          []string      `json:"secrets"`
+	RequireSha256    bool          `json:"requireSha256"`
+	DeduplicationTtl time.Duration `json:"deduplicationTtl"`
+	Recovery         Recovery      `json:"recovery"`
+}
 
 type GitHub struct {
+	AppId    int    `json:"appId"`
+	ClientId string `json:"clientId"`
+	Secret   string `json:"secret"`
+	Key      string `json:"key"`
+	// ApiUrl and WebUrl point the daemon at GitHub Enterprise Server or a GHE.com host, such as
+	// https://ghe.corp/api/v3 and https://ghe.corp
+	ApiUrl  string  `json:"apiUrl"`
+	WebUrl  string  `json:"webUrl"`
+	Webhook Webhook `json:"webhook"`
+}
+
+type Poll struct {
+	Interval time.Duration `json:"interval"`
+	// HourlyBudget is how many api requests an hour polling may use, an installation gets at least 5000
+	HourlyBudget int `json:"hourlyBudget"`
+}
+
+type Collector struct {
+	Enabled  bool          `json:"enabled"`
+	Interval time.Duration `json:"interval"`
+	// MaxRunnerAge is how long a runner container may run before it is considered stuck
+	MaxRunnerAge time.Duration `json:"maxRunnerAge"`
+	// RegistrationGracePeriod is how long a new runner gets to show up on GitHub before its container is removed
+	RegistrationGracePeriod time.Duration `json:"registrationGracePeriod"`
+}
+
+type Reconciler struct {
+	Interval time.Duration `json:"interval"`
+	// HourlyBudget is how many api requests an hour reconciling may use, on top of the budget of the poller
+	HourlyBudget int `json:"hourlyBudget"`
+	// QueueGracePeriod is how long a queued job may go without a runner before the reconciler dispatches one, the
+	// webhook usually gets there first
+	QueueGracePeriod time.Duration `json:"queueGracePeriod"`
+}
+
+type Runner struct {
+	// Version and Checksum pin the actions runner the runner image is built with
+	Version  string `json:"version"`
+	Checksum string `json:"checksum"`
+	// MinimumVersion makes the daemon warn about runner images older than it
+	MinimumVersion string `json:"minimumVersion"`
+	// Build builds the runner image on startup when the image of the pinned version is missing
+	Build bool `json:"build"`
+}
+
+// Registry holds the credentials for a private registry, either a username and password or an identity token
+type Registry struct {
+	Server        string `json:"server"`
+	Username      string `json:"username"`
+	Password      string `json:"password"`
+	IdentityToken string `json:"identityToken"`
+}
+
+const (
+	RunnerGroupVisibilityAll      RunnerGroupVisibility = "all"
+	RunnerGroupVisibilitySelected RunnerGroupVisibility = "selected"
+	RunnerGroupVisibilityPrivate  RunnerGroupVisibility = "private"
+)
+
+type RunnerGroupVisibility string
+
+// RunnerGroup declares a runner group of an organization, `runner-groups sync` creates it and keeps it in line
+type RunnerGroup struct {
+	Organization string                `json:"organization"`
+	Name         string                `json:"name"`
+	Visibility   RunnerGroupVisibility `json:"visibility"`
+	// Repositories are the names of the repositories of the organization that may use a group with selected
+	// visibility
+	Repositories             []string `json:"repositories"`
+	AllowsPublicRepositories bool     `json:"allowsPublicRepositories"`
+}
+
+type Store struct {
+	Path string `json:"path"`
 }
 
 type Config struct {
-	GitHub GitHub `json:"github"`
+	// InstanceId marks the containers this daemon owns, it is generated and persisted in the store when left empty
+	InstanceId string     `json:"instanceId"`
+	GitHub     GitHub     `json:"github"`
+	Poll       Poll       `json:"poll"`
+	Collector  Collector  `json:"collector"`
+	Reconciler Reconciler `json:"reconciler"`
+	Runner     Runner     `json:"runner"`
+	Registries []Registry `json:"registries"`
+	// DockerConfig is the path to a docker config.json to read registry credentials from, empty looks in the usual
+	// places
+	DockerConfig string        `json:"dockerConfig"`
+	RunnerGroups []RunnerGroup `json:"runnerGroups"`
+	Store        Store         `json:"store"`
+	Pools        []Pool        `json:"pools"`
 }
 
 func SetupConfig(configFilePath string) error {
@@ -28,6 +136,22 @@ func SetupConfig(configFilePath string) error {
 	venv.SetEnvPrefix(atlas.CONFIG_PREFIX)
 	venv.SetEnvKeyReplacer(strings.NewReplacer(".", "_", " ", ""))
 	venv.AutomaticEnv()
+	venv.SetDefault("github.apiUrl", atlas.DEFAULT_GITHUB_API_URL)
+	venv.SetDefault("github.webUrl", atlas.DEFAULT_GITHUB_WEB_URL)
+	venv.SetDefault("github.webhook.deduplicationTtl", 72*time.Hour)
+	venv.SetDefault("github.webhook.recovery.enabled", true)
+	venv.SetDefault("github.webhook.recovery.interval", 5*time.Minute)
+	venv.SetDefault("github.webhook.recovery.lookback", time.Hour)
+	venv.SetDefault("poll.interval", 30*time.Second)
+	venv.SetDefault("poll.hourlyBudget", 2500)
+	venv.SetDefault("reconciler.interval", 30*time.Second)
+	venv.SetDefault("reconciler.queueGracePeriod", time.Minute)
+	venv.SetDefault("reconciler.hourlyBudget", 1000)
+	venv.SetDefault("runner.version", atlas.DEFAULT_RUNNER_VERSION)
+	venv.SetDefault("collector.enabled", true)
+	venv.SetDefault("collector.interval", 5*time.Minute)
+	venv.SetDefault("collector.maxRunnerAge", 24*time.Hour)
+	venv.SetDefault("collector.registrationGracePeriod", 5*time.Minute)
 
 	if configFilePath == "" {
 		cfp, err := verifyConfigFile()
@@ -46,9 +170,42 @@ func SetupConfig(configFilePath string) error {
 
 	log.Debug("using config", venv.ConfigFileUsed())
 
+	config := &Config{}
+	err = venv.Unmarshal(config, func(decoderConfig *mapstructure.DecoderConfig) {
+		decoderConfig.TagName = "json"
+	})
+	if err != nil {
+		return err
+	}
+
+	setPoolDefaults(config.Pools)
+
+	for i := range config.RunnerGroups {
+		if config.RunnerGroups[i].Visibility == "" {
+			config.RunnerGroups[i].Visibility = RunnerGroupVisibilitySelected
+		}
+	}
+
+	// the checksum only goes without saying for the version the Dockerfile was written for
+	if config.Runner.Checksum == "" && config.Runner.Version == atlas.DEFAULT_RUNNER_VERSION {
+		config.Runner.Checksum = atlas.DEFAULT_RUNNER_CHECKSUM
+	}
+
+	err = validate(config)
+	if err != nil {
+		return err
+	}
+
+	instance = config
+
 	return nil
 }
 
+// Get returns the config that was loaded by SetupConfig
+func Get() *Config {
+	return instance
+}
+
 func verifyConfigFile() (string, error) {
 	userConfigDir, err := os.UserConfigDir()
 	if err != nil {
@@ -76,22 +233,30 @@ func verifyConfigFile() (string, error) {
 	return configFilePath, nil
 }
 
-func GetGitHubAppId() int {
-	return venv.GetInt("github.appId")
-}
+// GetSecrets returns every secret a webhook delivery may be signed with. The legacy single secret is still accepted
+// so existing configs keep working while a rotation is in progress. Empty secrets are left out, anyone could sign a
+// delivery with them.
+func (w *Webhook) GetSecrets() []string {
+	secrets := slices.DeleteFunc(slices.Clone(w.Secrets), func(secret string) bool {
+		return secret == ""
+	})
+	if w.Secret != "" {
+		secrets = append(secrets, w.Secret)
+	}
 
-func GetGitHubClientId() string {
-	return venv.GetString("github.clientid")
+	return secrets
 }
 
-func GetGitHubSecret() string {
-	return venv.GetString("github.secret")
-}
+// GetStorePath returns where the persistent state is kept, it defaults to a file next to the default config file
+func GetStorePath() (string, error) {
+	if instance.Store.Path != "" {
+		return instance.Store.Path, nil
+	}
 
-func GetGitHubKey() string {
-	return venv.GetString("github.key")
-}
+	userConfigDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
 
-func GetGitHubWebhookSecret() string {
-	return venv.GetString("github.webhook.secret")
+	return path.Join(userConfigDir, atlas.CONFIG_NAMESPACE, atlas.CONFIG_PREFIX, atlas.STORE_FILENAME), nil
 }
@@ -0,0 +1,285 @@
+package config
+
+import (
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"mirasynth.stream/github-runner/internal/atlas"
+)
+
+const (
+	PoolScopeRepository   PoolScope = "repository"
+	PoolScopeOrganization PoolScope = "organization"
+
+	// PoolRegistrationJit hands the runner a single use just-in-time config
+	PoolRegistrationJit PoolRegistration = "jit"
+	// PoolRegistrationToken hands the runner a registration token and lets it run config.sh
+	PoolRegistrationToken PoolRegistration = "token"
+)
+
+const (
+	// NetworkModeIsolated gives every runner a network of its own, which is removed along with the runner
+	NetworkModeIsolated NetworkMode = "isolated"
+	// NetworkModeBridge puts the runners on the default bridge, where they can reach each other
+	NetworkModeBridge NetworkMode = "bridge"
+
+	// EgressModeSidecar cuts the runner network off and routes everything through an allow list proxy sidecar
+	EgressModeSidecar EgressMode = "sidecar"
+	// EgressModeEnv points the proxy environment variables of the runner at an existing proxy, tools that ignore
+	// them can still reach anything
+	EgressModeEnv EgressMode = "env"
+)
+
+const (
+	// DockerModeDind runs a rootless docker daemon next to the runner and points DOCKER_HOST at it
+	DockerModeDind DockerMode = "dind"
+	// DockerModeBuildkit runs a rootless buildkit daemon next to the runner and points BUILDKIT_HOST at it
+	DockerModeBuildkit DockerMode = "buildkit"
+)
+
+const (
+	PullPolicyAlways       PullPolicy = "always"
+	PullPolicyIfNotPresent PullPolicy = "ifNotPresent"
+	PullPolicyNever        PullPolicy = "never"
+)
+
+type PoolScope string
+type PoolRegistration string
+type NetworkMode string
+type EgressMode string
+type DockerMode string
+type PullPolicy string
+
+type Ulimit struct {
+	// Name is the name of the limit without the RLIMIT_ prefix, such as nofile or nproc
+	Name string `json:"name"`
+	Soft int64  `json:"soft"`
+	Hard int64  `json:"hard"`
+}
+
+type Resources struct {
+	// Cpus is the cpu quota of a runner in cpus, fractions are allowed
+	Cpus float64 `json:"cpus"`
+	// CpuShares is the weight of a runner when the cpus are contended, docker defaults to 1024
+	CpuShares int64 `json:"cpuShares"`
+	// Memory is a size such as 512m or 4g
+	Memory string `json:"memory"`
+	// MemorySwap is the memory plus swap a runner may use, -1 allows unlimited swap
+	MemorySwap string `json:"memorySwap"`
+	// PidsLimit caps the number of processes in a runner, 0 leaves it unbounded
+	PidsLimit int64 `json:"pidsLimit"`
+	// Disk is the size of the writable layer of a runner, only storage drivers with quota support honour it
+	Disk    string   `json:"disk"`
+	Ulimits []Ulimit `json:"ulimits"`
+}
+
+type Tmpfs struct {
+	Path string `json:"path"`
+	// Options are the mount options, such as size=1g,mode=1777
+	Options string `json:"options"`
+}
+
+type Security struct {
+	// CapDrop is applied before CapAdd, so dropping ALL and adding a few back gives an explicit allow list
+	CapDrop         []string `json:"capDrop"`
+	CapAdd          []string `json:"capAdd"`
+	NoNewPrivileges bool     `json:"noNewPrivileges"`
+	// ReadOnlyRootfs mounts the image read-only, the runner writes to the Tmpfs mounts instead
+	ReadOnlyRootfs bool    `json:"readOnlyRootfs"`
+	Tmpfs          []Tmpfs `json:"tmpfs"`
+	// SeccompProfile is the path to a seccomp profile in json, or unconfined
+	SeccompProfile string `json:"seccompProfile"`
+	// AppArmorProfile is the name of a profile loaded on the host, or unconfined
+	AppArmorProfile string `json:"appArmorProfile"`
+	// UsernsMode is empty to follow the userns-remap of the daemon, or host to opt out of it
+	UsernsMode string `json:"usernsMode"`
+	// Runtime is the name of the OCI runtime, such as runc, crun or runsc, empty uses the default of the daemon
+	Runtime string `json:"runtime"`
+}
+
+type Egress struct {
+	// Mode is empty to leave egress unrestricted
+	Mode EgressMode `json:"mode"`
+	// Allow lists the hosts the sidecar lets through, *.example.com matches subdomains
+	Allow []string `json:"allow"`
+	// Image is the image of the sidecar, it has to run the proxy command of this binary
+	Image string `json:"image"`
+	// Proxy is the url of the proxy the runner environment points at in env mode
+	Proxy string `json:"proxy"`
+}
+
+type Network struct {
+	Mode   NetworkMode `json:"mode"`
+	Egress Egress      `json:"egress"`
+}
+
+type Docker struct {
+	// Mode is empty to run without a docker sidecar
+	Mode  DockerMode `json:"mode"`
+	Image string     `json:"image"`
+	// Privileged runs the dind sidecar privileged, for hosts where unconfined seccomp and apparmor profiles and
+	// /dev/fuse are not enough for rootless dockerd. A privileged sidecar can take over the host.
+	Privileged bool `json:"privileged"`
+}
+
+// Cache is a volume that outlives the runners of the pool, so the next job finds what the previous one downloaded
+type Cache struct {
+	Name string `json:"name"`
+	// Path is where the cache is mounted, a leading ~ stands for the home directory of the runner user
+	Path     string `json:"path"`
+	ReadOnly bool   `json:"readOnly"`
+	// CopyOnWrite gives every job a snapshot of the cache, what the job writes is thrown away afterwards
+	CopyOnWrite bool `json:"copyOnWrite"`
+	// MaxSize evicts the cache once it grows beyond the size, empty keeps it around for good
+	MaxSize string `json:"maxSize"`
+}
+
+type Pool struct {
+	Name   string   `json:"name"`
+	Labels []string `json:"labels"`
+	Image  string   `json:"image"`
+	// PullPolicy applies to the runner image and the images of its sidecars
+	PullPolicy   PullPolicy       `json:"pullPolicy"`
+	Scope        PoolScope        `json:"scope"`
+	Registration PoolRegistration `json:"registration"`
+	// Environment is a list of KEY=value pairs passed to the runner container
+	Environment []string  `json:"environment"`
+	Resources   Resources `json:"resources"`
+	Security    Security  `json:"security"`
+	Network     Network   `json:"network"`
+	Docker      Docker    `json:"docker"`
+	Caches      []Cache   `json:"caches"`
+	// Repository is the owner/name repository warm runners of a repository scoped pool register with
+	Repository string `json:"repository"`
+	// Organization is the organization warm runners of an organization scoped pool register with, job runners
+	// register with the organization of their job
+	Organization string `json:"organization"`
+	// RunnerGroup is the name of the organization runner group the runners register into, empty uses the Default group
+	RunnerGroup string `json:"runnerGroup"`
+	// WarmSize is the number of idle runners kept registered ahead of the jobs that will need them
+	WarmSize int `json:"warmSize"`
+	MinSize  int `json:"minSize"`
+	// MaxSize caps the number of runners in the pool, 0 leaves it unbounded
+	MaxSize int `json:"maxSize"`
+	// IdleTimeout is how long runners beyond the warm size may sit idle before they are scaled down
+	IdleTimeout time.Duration `json:"idleTimeout"`
+}
+
+// defaultLabels are the labels every self-hosted runner gets without asking for them
+func defaultLabels() []string {
+	arch := runtime.GOARCH
+	switch arch {
+	case "amd64":
+		arch = "x64"
+	case "386":
+		arch = "x86"
+	}
+
+	return []string{"self-hosted", "linux", arch}
+}
+
+// defaultTmpfs are the directories the runner writes to, which have to stay writable with a read-only rootfs
+func defaultTmpfs() []Tmpfs {
+	return []Tmpfs{
+		{Path: "/tmp", Options: "mode=1777"},
+		{Path: "/actions-runner/_work", Options: "mode=1777"},
+		{Path: "/actions-runner/_diag", Options: "mode=1777"},
+	}
+}
+
+func setPoolDefaults(pools []Pool) {
+	for i := range pools {
+		if pools[i].Image == "" {
+			pools[i].Image = atlas.DEFAULT_RUNNER_IMAGE
+		}
+
+		if pools[i].PullPolicy == "" {
+			pools[i].PullPolicy = PullPolicyIfNotPresent
+		}
+
+		if pools[i].Scope == "" {
+			pools[i].Scope = PoolScopeRepository
+		}
+
+		if pools[i].Registration == "" {
+			pools[i].Registration = PoolRegistrationJit
+		}
+
+		if pools[i].Network.Mode == "" {
+			pools[i].Network.Mode = NetworkModeIsolated
+		}
+
+		if pools[i].Network.Egress.Mode == EgressModeSidecar && pools[i].Network.Egress.Image == "" {
+			pools[i].Network.Egress.Image = atlas.DEFAULT_PROXY_IMAGE
+		}
+
+		if pools[i].Docker.Image == "" {
+			switch pools[i].Docker.Mode {
+			case DockerModeDind:
+				pools[i].Docker.Image = "docker:dind-rootless"
+			case DockerModeBuildkit:
+				pools[i].Docker.Image = "moby/buildkit:rootless"
+			}
+		}
+
+		if pools[i].Security.ReadOnlyRootfs && len(pools[i].Security.Tmpfs) == 0 {
+			pools[i].Security.Tmpfs = defaultTmpfs()
+		}
+	}
+}
+
+// RunnerLabels returns every label the runners of the pool end up with, including the implied ones
+func (p *Pool) RunnerLabels() []string {
+	return append(defaultLabels(), p.Labels...)
+}
+
+// Matches reports whether a runner of the pool can run a job with the runs-on labels, which is the case when the
+// runner has every one of the labels
+func (p *Pool) Matches(labels []string) bool {
+	runnerLabels := p.RunnerLabels()
+
+	for _, label := range labels {
+		if !slices.ContainsFunc(runnerLabels, func(runnerLabel string) bool {
+			return strings.EqualFold(runnerLabel, label)
+		}) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// MatchPool returns the first pool that can run a job with the runs-on labels, or nil if there is none
+func (c *Config) MatchPool(labels []string) *Pool {
+	for i := range c.Pools {
+		if c.Pools[i].Matches(labels) {
+			return &c.Pools[i]
+		}
+	}
+
+	return nil
+}
+
+// GetCache returns the cache with the name, or nil if there is none
+func (p *Pool) GetCache(name string) *Cache {
+	for i := range p.Caches {
+		if p.Caches[i].Name == name {
+			return &p.Caches[i]
+		}
+	}
+
+	return nil
+}
+
+// GetPool returns the pool with the name, or nil if there is none
+func (c *Config) GetPool(name string) *Pool {
+	for i := range c.Pools {
+		if c.Pools[i].Name == name {
+			return &c.Pools[i]
+		}
+	}
+
+	return nil
+}
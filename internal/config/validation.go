@@ -0,0 +1,435 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/docker/go-units"
+)
+
+var poolNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+var cacheNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+var versionPattern = regexp.MustCompile(`^[0-9]+\.[0-9]+\.[0-9]+$`)
+var organizationPattern = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+var repositoryPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+$`)
+var checksumPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// ValidationError points at the exact path in the config file that holds an invalid value
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+type validator struct {
+	errs []error
+}
+
+func (v *validator) fail(path string, format string, args ...any) {
+	v.errs = append(v.errs, &ValidationError{
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// validate checks the whole config and returns every problem it finds at once
+func validate(config *Config) error {
+	v := &validator{}
+
+	if config.GitHub.AppId <= 0 {
+		v.fail("github.appId", "must be the id of the GitHub App")
+	}
+
+	validateBaseUrl(v, "github.apiUrl", config.GitHub.ApiUrl)
+	validateBaseUrl(v, "github.webUrl", config.GitHub.WebUrl)
+
+	if config.Reconciler.Interval <= 0 {
+		v.fail("reconciler.interval", "must be a positive duration")
+	}
+
+	if config.Collector.Enabled && config.Collector.Interval <= 0 {
+		v.fail("collector.interval", "must be a positive duration")
+	}
+
+	if !versionPattern.MatchString(config.Runner.Version) {
+		v.fail("runner.version", "must look like 2.316.1, got %q", config.Runner.Version)
+	}
+
+	if !checksumPattern.MatchString(config.Runner.Checksum) {
+		v.fail("runner.checksum", "must be the sha256 of the runner release archive in hex")
+	}
+
+	if config.Runner.MinimumVersion != "" && !versionPattern.MatchString(config.Runner.MinimumVersion) {
+		v.fail("runner.minimumVersion", "must look like 2.316.1, got %q", config.Runner.MinimumVersion)
+	}
+
+	for i, registry := range config.Registries {
+		if registry.Server == "" {
+			v.fail(fmt.Sprintf("registries[%d].server", i), "must not be empty")
+		}
+
+		if registry.IdentityToken == "" && registry.Username == "" {
+			v.fail(fmt.Sprintf("registries[%d]", i), "needs a username and password or an identityToken")
+		}
+	}
+
+	groups := map[string]int{}
+	for i, group := range config.RunnerGroups {
+		path := fmt.Sprintf("runnerGroups[%d]", i)
+		validateRunnerGroup(v, path, &group)
+
+		key := fmt.Sprintf("%s/%s", group.Organization, group.Name)
+		if first, ok := groups[key]; ok {
+			v.fail(path+".name", "%q is already declared by runnerGroups[%d]", group.Name, first)
+		}
+		groups[key] = i
+	}
+
+	names := map[string]int{}
+	for i, pool := range config.Pools {
+		validatePool(v, fmt.Sprintf("pools[%d]", i), &pool)
+
+		if first, ok := names[pool.Name]; ok {
+			v.fail(fmt.Sprintf("pools[%d].name", i), "%q is already used by pools[%d]", pool.Name, first)
+		}
+		names[pool.Name] = i
+	}
+
+	// a copy on write cache is the lower dir of an overlay, which must not change underneath it. The volume of a cache
+	// is named after its pool and itself, so pool a-b with cache c ends up on the same volume as pool a with cache
+	// b-c, and both have to mount it the same way.
+	volumes := map[string]string{}
+	copyOnWrite := map[string]bool{}
+	for i, pool := range config.Pools {
+		for j, cache := range pool.Caches {
+			path := fmt.Sprintf("pools[%d].caches[%d]", i, j)
+			volume := fmt.Sprintf("%s-%s", pool.Name, cache.Name)
+			first, ok := volumes[volume]
+			if !ok {
+				volumes[volume] = path
+				copyOnWrite[volume] = cache.CopyOnWrite
+				continue
+			}
+
+			if copyOnWrite[volume] != cache.CopyOnWrite {
+				v.fail(path+".copyOnWrite", "must match %s.copyOnWrite, both caches share a volume", first)
+			}
+		}
+	}
+
+	return errors.Join(v.errs...)
+}
+
+// ValidateWebhook checks what receiving webhooks needs on top of the rest of the config, polling and building images
+// get by without a webhook secret
+func ValidateWebhook() error {
+	v := &validator{}
+
+	if len(instance.GitHub.Webhook.GetSecrets()) == 0 {
+		v.fail("github.webhook.secrets", "at least one non-empty secret is required to verify deliveries")
+	}
+
+	return errors.Join(v.errs...)
+}
+
+func validateBaseUrl(v *validator, path string, value string) {
+	parsed, err := url.Parse(value)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		v.fail(path, "must be an absolute http or https url, got %q", value)
+		return
+	}
+
+	if parsed.RawQuery != "" || parsed.Fragment != "" {
+		v.fail(path, "must not have a query or fragment, got %q", value)
+	}
+}
+
+func validateRunnerGroup(v *validator, path string, group *RunnerGroup) {
+	if !organizationPattern.MatchString(group.Organization) {
+		v.fail(path+".organization", "must be an organization login, got %q", group.Organization)
+	}
+
+	if group.Name == "" {
+		v.fail(path+".name", "must not be empty")
+	}
+
+	switch group.Visibility {
+	case RunnerGroupVisibilityAll, RunnerGroupVisibilityPrivate:
+		if len(group.Repositories) > 0 {
+			v.fail(path+".repositories", "only apply to %q visibility", RunnerGroupVisibilitySelected)
+		}
+	case RunnerGroupVisibilitySelected:
+	default:
+		v.fail(path+".visibility", "must be %q, %q or %q, got %q", RunnerGroupVisibilityAll, RunnerGroupVisibilitySelected, RunnerGroupVisibilityPrivate, group.Visibility)
+	}
+
+	for i, repository := range group.Repositories {
+		if repository == "" || strings.Contains(repository, "/") {
+			v.fail(fmt.Sprintf("%s.repositories[%d]", path, i), "must be the name of a repository of the organization, got %q", repository)
+		}
+	}
+}
+
+func validatePool(v *validator, path string, pool *Pool) {
+	if !poolNamePattern.MatchString(pool.Name) {
+		v.fail(path+".name", "must be lowercase letters, digits, '_', '.' or '-', got %q", pool.Name)
+	}
+
+	if len(pool.Labels) == 0 {
+		v.fail(path+".labels", "at least one label is required to route jobs to the pool")
+	}
+
+	for i, label := range pool.Labels {
+		if label == "" {
+			v.fail(fmt.Sprintf("%s.labels[%d]", path, i), "must not be empty")
+		}
+	}
+
+	for i, variable := range pool.Environment {
+		if !strings.Contains(variable, "=") || strings.HasPrefix(variable, "=") {
+			v.fail(fmt.Sprintf("%s.environment[%d]", path, i), "must be a KEY=value pair, got %q", variable)
+		}
+	}
+
+	switch pool.Scope {
+	case PoolScopeRepository, PoolScopeOrganization:
+	default:
+		v.fail(path+".scope", "must be %q or %q, got %q", PoolScopeRepository, PoolScopeOrganization, pool.Scope)
+	}
+
+	if pool.PullPolicy != PullPolicyAlways && pool.PullPolicy != PullPolicyIfNotPresent && pool.PullPolicy != PullPolicyNever {
+		v.fail(path+".pullPolicy", "must be %q, %q or %q, got %q", PullPolicyAlways, PullPolicyIfNotPresent, PullPolicyNever, pool.PullPolicy)
+	}
+
+	if pool.Registration != PoolRegistrationJit && pool.Registration != PoolRegistrationToken {
+		v.fail(path+".registration", "must be %q or %q, got %q", PoolRegistrationJit, PoolRegistrationToken, pool.Registration)
+	}
+
+	if pool.Resources.Cpus < 0 {
+		v.fail(path+".resources.cpus", "must not be negative")
+	}
+
+	if pool.Resources.CpuShares < 0 {
+		v.fail(path+".resources.cpuShares", "must not be negative")
+	}
+
+	var memory int64
+	if pool.Resources.Memory != "" {
+		var err error
+		memory, err = units.RAMInBytes(pool.Resources.Memory)
+		if err != nil {
+			v.fail(path+".resources.memory", "%s", err)
+		}
+	}
+
+	if pool.Resources.MemorySwap != "" && pool.Resources.MemorySwap != "-1" {
+		memorySwap, err := units.RAMInBytes(pool.Resources.MemorySwap)
+		switch {
+		case err != nil:
+			v.fail(path+".resources.memorySwap", "%s", err)
+		case pool.Resources.Memory == "":
+			v.fail(path+".resources.memorySwap", "requires memory to be set")
+		case memorySwap < memory:
+			v.fail(path+".resources.memorySwap", "must not be smaller than memory (%s)", pool.Resources.Memory)
+		}
+	}
+
+	if pool.Resources.PidsLimit < 0 {
+		v.fail(path+".resources.pidsLimit", "must not be negative")
+	}
+
+	if pool.Resources.Disk != "" {
+		_, err := units.RAMInBytes(pool.Resources.Disk)
+		if err != nil {
+			v.fail(path+".resources.disk", "%s", err)
+		}
+	}
+
+	for j, ulimit := range pool.Resources.Ulimits {
+		_, err := units.ParseUlimit(fmt.Sprintf("%s=%d:%d", ulimit.Name, ulimit.Soft, ulimit.Hard))
+		if err != nil {
+			v.fail(fmt.Sprintf("%s.resources.ulimits[%d]", path, j), "%s", err)
+		}
+	}
+
+	validateSecurity(v, path+".security", pool)
+	validateNetwork(v, path+".network", pool)
+
+	validateCaches(v, path+".caches", pool)
+
+	switch pool.Docker.Mode {
+	case "":
+	case DockerModeDind, DockerModeBuildkit:
+		// the runner reaches the sidecar by its alias, which only resolves on a network of its own
+		if pool.Network.Mode != NetworkModeIsolated {
+			v.fail(path+".docker.mode", "requires the %q network mode", NetworkModeIsolated)
+		}
+	default:
+		v.fail(path+".docker.mode", "must be empty, %q or %q, got %q", DockerModeDind, DockerModeBuildkit, pool.Docker.Mode)
+	}
+
+	if pool.Docker.Privileged && pool.Docker.Mode != DockerModeDind {
+		v.fail(path+".docker.privileged", "requires the %q docker mode", DockerModeDind)
+	}
+
+	if pool.WarmSize < 0 {
+		v.fail(path+".warmSize", "must not be negative")
+	}
+
+	if pool.MaxSize > 0 && pool.WarmSize > pool.MaxSize {
+		v.fail(path+".warmSize", "must not be larger than maxSize (%d)", pool.MaxSize)
+	}
+
+	if pool.Repository != "" && !repositoryPattern.MatchString(pool.Repository) {
+		v.fail(path+".repository", "must be owner/name, got %q", pool.Repository)
+	}
+
+	// a warm runner has no job yet to tell it where to register
+	if pool.WarmSize > 0 && pool.Scope == PoolScopeRepository && pool.Repository == "" {
+		v.fail(path+".repository", "is required for warm runners of a %q scoped pool", PoolScopeRepository)
+	}
+
+	if pool.Organization != "" && !organizationPattern.MatchString(pool.Organization) {
+		v.fail(path+".organization", "must be an organization login, got %q", pool.Organization)
+	}
+
+	// repositories only have the Default group
+	if pool.RunnerGroup != "" && pool.Scope != PoolScopeOrganization {
+		v.fail(path+".runnerGroup", "requires %q scope", PoolScopeOrganization)
+	}
+
+	if pool.WarmSize > 0 && pool.Scope == PoolScopeOrganization && pool.Organization == "" {
+		v.fail(path+".organization", "is required for warm runners of a %q scoped pool", PoolScopeOrganization)
+	}
+
+	if pool.MinSize < 0 {
+		v.fail(path+".minSize", "must not be negative")
+	}
+
+	if pool.MaxSize < 0 {
+		v.fail(path+".maxSize", "must not be negative")
+	}
+
+	if pool.MaxSize > 0 && pool.MinSize > pool.MaxSize {
+		v.fail(path+".minSize", "must not be larger than maxSize (%d)", pool.MaxSize)
+	}
+
+	if pool.IdleTimeout < 0 {
+		v.fail(path+".idleTimeout", "must not be negative")
+	}
+}
+
+func validateSecurity(v *validator, path string, pool *Pool) {
+	security := &pool.Security
+
+	for j, capability := range security.CapDrop {
+		if strings.TrimSpace(capability) == "" {
+			v.fail(fmt.Sprintf("%s.capDrop[%d]", path, j), "must not be empty")
+		}
+	}
+
+	for j, capability := range security.CapAdd {
+		if strings.TrimSpace(capability) == "" {
+			v.fail(fmt.Sprintf("%s.capAdd[%d]", path, j), "must not be empty")
+		}
+	}
+
+	// config.sh writes the runner credentials next to the runner binaries
+	if security.ReadOnlyRootfs && pool.Registration == PoolRegistrationToken {
+		v.fail(path+".readOnlyRootfs", "requires %q registration", PoolRegistrationJit)
+	}
+
+	for j, tmpfs := range security.Tmpfs {
+		if !filepath.IsAbs(tmpfs.Path) {
+			v.fail(fmt.Sprintf("%s.tmpfs[%d].path", path, j), "must be an absolute path, got %q", tmpfs.Path)
+		}
+	}
+
+	if security.SeccompProfile != "" && security.SeccompProfile != "unconfined" {
+		profile, err := os.ReadFile(security.SeccompProfile)
+		switch {
+		case err != nil:
+			v.fail(path+".seccompProfile", "%s", err)
+		case !json.Valid(profile):
+			v.fail(path+".seccompProfile", "%s is not valid json", security.SeccompProfile)
+		}
+	}
+
+	if security.UsernsMode != "" && security.UsernsMode != "host" {
+		v.fail(path+".usernsMode", "must be empty or %q, got %q", "host", security.UsernsMode)
+	}
+}
+
+func validateNetwork(v *validator, path string, pool *Pool) {
+	network := &pool.Network
+
+	if network.Mode != NetworkModeIsolated && network.Mode != NetworkModeBridge {
+		v.fail(path+".mode", "must be %q or %q, got %q", NetworkModeIsolated, NetworkModeBridge, network.Mode)
+	}
+
+	switch network.Egress.Mode {
+	case "":
+	case EgressModeSidecar:
+		// on the default bridge the sidecar could not stop the runner from going around it
+		if network.Mode != NetworkModeIsolated {
+			v.fail(path+".egress.mode", "%q requires the %q network mode", EgressModeSidecar, NetworkModeIsolated)
+		}
+
+		if len(network.Egress.Allow) == 0 {
+			v.fail(path+".egress.allow", "must not be empty")
+		}
+
+		for j, host := range network.Egress.Allow {
+			if strings.TrimSpace(host) == "" || strings.Contains(host, ",") {
+				v.fail(fmt.Sprintf("%s.egress.allow[%d]", path, j), "must be a host name, got %q", host)
+			}
+		}
+	case EgressModeEnv:
+		proxyUrl, err := url.Parse(network.Egress.Proxy)
+		if err != nil || proxyUrl.Scheme == "" || proxyUrl.Host == "" {
+			v.fail(path+".egress.proxy", "must be an absolute url, got %q", network.Egress.Proxy)
+		}
+	default:
+		v.fail(path+".egress.mode", "must be empty, %q or %q, got %q", EgressModeSidecar, EgressModeEnv, network.Egress.Mode)
+	}
+}
+
+func validateCaches(v *validator, path string, pool *Pool) {
+	names := map[string]int{}
+	for j, cache := range pool.Caches {
+		cachePath := fmt.Sprintf("%s[%d]", path, j)
+
+		if !cacheNamePattern.MatchString(cache.Name) {
+			v.fail(cachePath+".name", "must match %s, got %q", cacheNamePattern, cache.Name)
+		}
+
+		if first, ok := names[cache.Name]; ok {
+			v.fail(cachePath+".name", "%q is already used by %s[%d]", cache.Name, path, first)
+		}
+		names[cache.Name] = j
+
+		if !filepath.IsAbs(cache.Path) && !strings.HasPrefix(cache.Path, "~/") {
+			v.fail(cachePath+".path", "must be an absolute path or start with ~/, got %q", cache.Path)
+		}
+
+		if cache.ReadOnly && cache.CopyOnWrite {
+			v.fail(cachePath+".copyOnWrite", "cannot be combined with readOnly")
+		}
+
+		if cache.MaxSize != "" {
+			_, err := units.RAMInBytes(cache.MaxSize)
+			if err != nil {
+				v.fail(cachePath+".maxSize", "%s", err)
+			}
+		}
+	}
+}
@@ -1,35 +1,138 @@
 package container
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"os"
-	"runtime"
+	"strings"
+	"time"
 
 	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
 	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/go-units"
 )
 
+type Ulimit struct {
+	Name string `json:"name"`
+	Soft int64  `json:"soft"`
+	Hard int64  `json:"hard"`
+}
+
+// Resources limits what a container may use, zero values leave the docker defaults in place
+type Resources struct {
+	NanoCpus   int64 `json:"nanoCpus"`
+	CpuShares  int64 `json:"cpuShares"`
+	Memory     int64 `json:"memory"`
+	MemorySwap int64 `json:"memorySwap"`
+	PidsLimit  int64 `json:"pidsLimit"`
+	// StorageSize is passed on as the size storage option, such as 20g
+	StorageSize string   `json:"storageSize"`
+	Ulimits     []Ulimit `json:"ulimits"`
+}
+
+type Security struct {
+	CapDrop         []string `json:"capDrop"`
+	CapAdd          []string `json:"capAdd"`
+	NoNewPrivileges bool     `json:"noNewPrivileges"`
+	ReadOnlyRootfs  bool     `json:"readOnlyRootfs"`
+	// Tmpfs maps a path in the container onto its mount options
+	Tmpfs map[string]string `json:"tmpfs"`
+	// SeccompProfile is the path to a seccomp profile in json, or unconfined
+	SeccompProfile  string `json:"seccompProfile"`
+	AppArmorProfile string `json:"appArmorProfile"`
+	UsernsMode      string `json:"usernsMode"`
+	Runtime         string `json:"runtime"`
+	// UnmaskSystemPaths leaves /proc and /sys unmasked and writable, which nested container runtimes need to mount
+	// them again in a namespace of their own
+	UnmaskSystemPaths bool `json:"unmaskSystemPaths"`
+	// Devices are host devices, such as /dev/fuse, passed into the container at the same path
+	Devices []string `json:"devices"`
+	// Privileged is meant for sidecars such as docker in docker, never for the runner itself
+	Privileged bool `json:"privileged"`
+}
+
 type Options struct {
-	Name        string   `json:"string"`
-	ImageName   string   `json:"imageName"`
-	Command     []string `json:"command"`
-	Entrypoint  []string `json:"entrypoint"`
-	Environment []string `json:"environment"`
+	Name        string            `json:"string"`
+	ImageName   string            `json:"imageName"`
+	Command     []string          `json:"command"`
+	Entrypoint  []string          `json:"entrypoint"`
+	Environment []string          `json:"environment"`
+	Labels      map[string]string `json:"labels"`
+	Resources   Resources         `json:"resources"`
+	Security    Security          `json:"security"`
+	// Network is the network the container starts on, empty leaves it on the default bridge
+	Network        string   `json:"network"`
+	NetworkAliases []string `json:"networkAliases"`
+	// Sidecars are created, started and removed together with the container
+	Sidecars []Sidecar `json:"sidecars"`
+	Mounts   []Mount   `json:"mounts"`
+	// User overrides the user of the image
+	User       string     `json:"user"`
+	PullPolicy PullPolicy `json:"pullPolicy"`
+}
+
+// Info describes a container, Inspect fills in every field while List only knows what the summary carries
+type Info struct {
+	Id         string            `json:"id"`
+	Name       string            `json:"name"`
+	ImageName  string            `json:"imageName"`
+	Labels     map[string]string `json:"labels"`
+	State      string            `json:"state"`
+	Running    bool              `json:"running"`
+	ExitCode   int               `json:"exitCode"`
+	CreatedAt  time.Time         `json:"createdAt"`
+	StartedAt  time.Time         `json:"startedAt"`
+	FinishedAt time.Time         `json:"finishedAt"`
 }
 
 type Container interface {
+	// Pull makes sure the image is present, pulling it when the policy asks for it
+	Pull(context.Context, string, PullPolicy) error
+	Build(context.Context, *BuildOptions) error
+	// InspectImage returns nil when the image is not present
+	InspectImage(context.Context, string) (*ImageInfo, error)
 	Create(context.Context, *Options) (string, error)
+	// Start returns as soon as the container is running
 	Start(context.Context, string) error
+	// Stop asks the container to stop and kills it once the timeout has passed
+	Stop(context.Context, string, time.Duration) error
+	Remove(context.Context, string) error
+	// Wait blocks until the container is no longer running and returns its exit code
+	Wait(context.Context, string) (int64, error)
+	Inspect(context.Context, string) (*Info, error)
+	// List returns every container, running or not, that carries all the labels
+	List(context.Context, map[string]string) ([]Info, error)
+	// Logs returns the stdout and stderr of the container, following them until it exits when follow is set
+	Logs(context.Context, string, bool) (io.ReadCloser, error)
+
+	CreateNetwork(context.Context, *NetworkOptions) (string, error)
+	RemoveNetwork(context.Context, string) error
+	// ConnectNetwork attaches a container to one more network, reachable there under the aliases
+	ConnectNetwork(ctx context.Context, networkId string, containerId string, aliases []string) error
+	// ListNetworks returns every network that carries all the labels
+	ListNetworks(context.Context, map[string]string) ([]NetworkInfo, error)
+
+	// ListVolumes returns every volume that carries all the labels
+	ListVolumes(context.Context, map[string]string) ([]VolumeInfo, error)
+	RemoveVolume(context.Context, string) error
+	Close() error
 }
 
 type implementation struct {
 	client *client.Client
+	auths  []RegistryAuth
 }
 
-func New() (Container, error) {
+// New connects to the docker daemon from the environment, the auths are used to pull from private registries
+func New(auths []RegistryAuth) (Container, error) {
 	c, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
 	if err != nil {
 		return nil, err
@@ -37,26 +140,36 @@ func New() (Container, error) {
 
 	impl := &implementation{
 		client: c,
+		auths:  auths,
 	}
 
-	runtime.SetFinalizer(impl, func(impl implementation) {
-		if impl.client == nil {
-			return
-		}
-
-		defer impl.client.Close()
-	})
-
 	return impl, nil
 }
 
+// Close closes the connection to the docker daemon
+func (c *implementation) Close() error {
+	return c.client.Close()
+}
+
+// Create creates the container and then its sidecars, so they can be labelled with its id
 func (c *implementation) Create(ctx context.Context, options *Options) (string, error) {
-	reader, err := c.client.ImagePull(ctx, options.ImageName, image.PullOptions{})
+	containerId, err := c.create(ctx, options)
 	if err != nil {
 		return "", err
 	}
 
-	_, err = io.Copy(os.Stdout, reader)
+	for i := range options.Sidecars {
+		err = c.createSidecar(ctx, containerId, options.Network, &options.Sidecars[i])
+		if err != nil {
+			return "", errors.Join(err, c.Remove(ctx, containerId))
+		}
+	}
+
+	return containerId, nil
+}
+
+func (c *implementation) create(ctx context.Context, options *Options) (string, error) {
+	err := c.Pull(ctx, options.ImageName, options.PullPolicy)
 	if err != nil {
 		return "", err
 	}
@@ -66,8 +179,56 @@ func (c *implementation) Create(ctx context.Context, options *Options) (string,
 		Cmd:        options.Command,
 		Entrypoint: options.Entrypoint,
 		Env:        options.Environment,
+		Labels:     options.Labels,
+		User:       options.User,
+	}
+	securityOptions, err := newSecurityOptions(&options.Security)
+	if err != nil {
+		return "", err
+	}
+
+	mounts, err := c.mounts(ctx, options)
+	if err != nil {
+		return "", err
+	}
+
+	hostConfig := &container.HostConfig{
+		Mounts:         mounts,
+		Resources:      newHostResources(&options.Resources),
+		CapDrop:        options.Security.CapDrop,
+		CapAdd:         options.Security.CapAdd,
+		SecurityOpt:    securityOptions,
+		ReadonlyRootfs: options.Security.ReadOnlyRootfs,
+		Tmpfs:          options.Security.Tmpfs,
+		UsernsMode:     container.UsernsMode(options.Security.UsernsMode),
+		Runtime:        options.Security.Runtime,
+		Privileged:     options.Security.Privileged,
+	}
+
+	var networkingConfig *network.NetworkingConfig
+	if options.Network != "" {
+		hostConfig.NetworkMode = container.NetworkMode(options.Network)
+		networkingConfig = &network.NetworkingConfig{
+			EndpointsConfig: map[string]*network.EndpointSettings{
+				options.Network: {Aliases: options.NetworkAliases},
+			},
+		}
+	}
+	if options.Security.UnmaskSystemPaths {
+		hostConfig.MaskedPaths = []string{}
+		hostConfig.ReadonlyPaths = []string{}
+	}
+	for _, device := range options.Security.Devices {
+		hostConfig.Devices = append(hostConfig.Devices, container.DeviceMapping{
+			PathOnHost:        device,
+			PathInContainer:   device,
+			CgroupPermissions: "rwm",
+		})
 	}
-	createResponse, err := c.client.ContainerCreate(ctx, containerConfig, nil, nil, nil, options.Name)
+	if options.Resources.StorageSize != "" {
+		hostConfig.StorageOpt = map[string]string{"size": options.Resources.StorageSize}
+	}
+	createResponse, err := c.client.ContainerCreate(ctx, containerConfig, hostConfig, networkingConfig, nil, options.Name)
 	if err != nil {
 		return "", err
 	}
@@ -75,23 +236,223 @@ func (c *implementation) Create(ctx context.Context, options *Options) (string,
 	return createResponse.ID, nil
 }
 
+func newHostResources(resources *Resources) container.Resources {
+	hostResources := container.Resources{
+		NanoCPUs:   resources.NanoCpus,
+		CPUShares:  resources.CpuShares,
+		Memory:     resources.Memory,
+		MemorySwap: resources.MemorySwap,
+	}
+
+	if resources.PidsLimit > 0 {
+		hostResources.PidsLimit = &resources.PidsLimit
+	}
+
+	for _, ulimit := range resources.Ulimits {
+		hostResources.Ulimits = append(hostResources.Ulimits, &units.Ulimit{
+			Name: ulimit.Name,
+			Soft: ulimit.Soft,
+			Hard: ulimit.Hard,
+		})
+	}
+
+	return hostResources
+}
+
+// newSecurityOptions builds the security-opt list, the daemon expects the seccomp profile itself rather than its path
+func newSecurityOptions(security *Security) ([]string, error) {
+	var securityOptions []string
+
+	if security.NoNewPrivileges {
+		securityOptions = append(securityOptions, "no-new-privileges")
+	}
+
+	switch security.SeccompProfile {
+	case "":
+	case "unconfined":
+		securityOptions = append(securityOptions, "seccomp=unconfined")
+	default:
+		profile, err := os.ReadFile(security.SeccompProfile)
+		if err != nil {
+			return nil, err
+		}
+
+		compactProfile := &bytes.Buffer{}
+		err = json.Compact(compactProfile, profile)
+		if err != nil {
+			return nil, fmt.Errorf("seccomp profile %s is not valid json, %w", security.SeccompProfile, err)
+		}
+
+		securityOptions = append(securityOptions, fmt.Sprintf("seccomp=%s", compactProfile))
+	}
+
+	if security.AppArmorProfile != "" {
+		securityOptions = append(securityOptions, fmt.Sprintf("apparmor=%s", security.AppArmorProfile))
+	}
+
+	return securityOptions, nil
+}
+
+// Start starts the sidecars ahead of the container, so whatever they serve is coming up by the time it needs it
 func (c *implementation) Start(ctx context.Context, containerId string) error {
-	containerStartOptions := container.StartOptions{}
-	err := c.client.ContainerStart(ctx, containerId, containerStartOptions)
+	sidecars, err := c.listSidecars(ctx, containerId)
 	if err != nil {
 		return err
 	}
 
-	containerLogsOptions := container.LogsOptions{ShowStdout: true, Follow: true}
-	out, err := c.client.ContainerLogs(ctx, containerId, containerLogsOptions)
+	for _, sidecar := range sidecars {
+		err = c.client.ContainerStart(ctx, sidecar.Id, containerStartOptions())
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.client.ContainerStart(ctx, containerId, containerStartOptions())
+}
+
+func containerStartOptions() container.StartOptions {
+	return container.StartOptions{}
+}
+
+func (c *implementation) Stop(ctx context.Context, containerId string, timeout time.Duration) error {
+	timeoutSeconds := int(timeout.Seconds())
+	containerStopOptions := container.StopOptions{Timeout: &timeoutSeconds}
+	return c.client.ContainerStop(ctx, containerId, containerStopOptions)
+}
+
+// Remove force removes a container and its sidecars along with their anonymous volumes and snapshots, so nothing a
+// job wrote survives it. Removing a container that is already gone is not an error.
+func (c *implementation) Remove(ctx context.Context, containerId string) error {
+	sidecars, err := c.listSidecars(ctx, containerId)
 	if err != nil {
 		return err
 	}
 
-	_, err = stdcopy.StdCopy(os.Stdout, os.Stderr, out)
+	for _, sidecar := range sidecars {
+		err = c.remove(ctx, sidecar.Id)
+		if err != nil {
+			return err
+		}
+	}
+
+	// the snapshots are labelled with the name of the container, which is only known before it goes
+	info, err := c.Inspect(ctx, containerId)
+	if errdefs.IsNotFound(err) {
+		return nil
+	}
 	if err != nil {
 		return err
 	}
 
-	return nil
+	err = c.remove(ctx, containerId)
+	if err != nil {
+		return err
+	}
+
+	return c.removeSnapshots(ctx, info.Name)
+}
+
+func (c *implementation) remove(ctx context.Context, containerId string) error {
+	containerRemoveOptions := container.RemoveOptions{Force: true, RemoveVolumes: true}
+	err := c.client.ContainerRemove(ctx, containerId, containerRemoveOptions)
+	if errdefs.IsNotFound(err) {
+		return nil
+	}
+
+	return err
+}
+
+func (c *implementation) Wait(ctx context.Context, containerId string) (int64, error) {
+	waitResponse, errs := c.client.ContainerWait(ctx, containerId, container.WaitConditionNotRunning)
+
+	select {
+	case response := <-waitResponse:
+		if response.Error != nil {
+			return response.StatusCode, fmt.Errorf("waiting for the container failed, %s", response.Error.Message)
+		}
+		return response.StatusCode, nil
+	case err := <-errs:
+		return 0, err
+	}
+}
+
+func (c *implementation) Inspect(ctx context.Context, containerId string) (*Info, error) {
+	inspectResponse, err := c.client.ContainerInspect(ctx, containerId)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &Info{
+		Id:   inspectResponse.ID,
+		Name: strings.TrimPrefix(inspectResponse.Name, "/"),
+	}
+
+	if inspectResponse.Config != nil {
+		info.ImageName = inspectResponse.Config.Image
+		info.Labels = inspectResponse.Config.Labels
+	}
+
+	info.CreatedAt, _ = time.Parse(time.RFC3339Nano, inspectResponse.Created)
+
+	if inspectResponse.State != nil {
+		info.State = inspectResponse.State.Status
+		info.Running = inspectResponse.State.Running
+		info.ExitCode = inspectResponse.State.ExitCode
+		info.StartedAt, _ = time.Parse(time.RFC3339Nano, inspectResponse.State.StartedAt)
+		info.FinishedAt, _ = time.Parse(time.RFC3339Nano, inspectResponse.State.FinishedAt)
+	}
+
+	return info, nil
+}
+
+func (c *implementation) List(ctx context.Context, labels map[string]string) ([]Info, error) {
+	labelFilters := filters.NewArgs()
+	for key, value := range labels {
+		labelFilters.Add("label", fmt.Sprintf("%s=%s", key, value))
+	}
+
+	containerListOptions := container.ListOptions{All: true, Filters: labelFilters}
+	summaries, err := c.client.ContainerList(ctx, containerListOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]Info, 0, len(summaries))
+	for _, summary := range summaries {
+		info := Info{
+			Id:        summary.ID,
+			ImageName: summary.Image,
+			Labels:    summary.Labels,
+			State:     summary.State,
+			Running:   summary.State == "running",
+			CreatedAt: time.Unix(summary.Created, 0),
+		}
+
+		if len(summary.Names) > 0 {
+			info.Name = strings.TrimPrefix(summary.Names[0], "/")
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+// Logs demultiplexes the docker log stream, so the reader returns plain text
+func (c *implementation) Logs(ctx context.Context, containerId string, follow bool) (io.ReadCloser, error) {
+	containerLogsOptions := container.LogsOptions{ShowStdout: true, ShowStderr: true, Follow: follow}
+	out, err := c.client.ContainerLogs(ctx, containerId, containerLogsOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		defer out.Close()
+
+		_, err := stdcopy.StdCopy(writer, writer, out)
+		writer.CloseWithError(err)
+	}()
+
+	return reader, nil
 }
@@ -0,0 +1,297 @@
+package container
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/jsonmessage"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	PullAlways PullPolicy = "always"
+	// PullIfNotPresent only pulls images the daemon does not have yet, it is what an empty policy means
+	PullIfNotPresent PullPolicy = "ifNotPresent"
+	// PullNever relies on the image being there already, which suits images that were only ever built locally
+	PullNever PullPolicy = "never"
+)
+
+type PullPolicy string
+
+// RegistryAuth holds the credentials for a registry, either a username and password or an identity token
+type RegistryAuth struct {
+	Server        string `json:"server"`
+	Username      string `json:"username"`
+	Password      string `json:"password"`
+	IdentityToken string `json:"identityToken"`
+}
+
+type BuildOptions struct {
+	// Context is a tar archive of the build context
+	Context    io.Reader         `json:"-"`
+	Dockerfile string            `json:"dockerfile"`
+	Tags       []string          `json:"tags"`
+	BuildArgs  map[string]string `json:"buildArgs"`
+	Labels     map[string]string `json:"labels"`
+}
+
+type ImageInfo struct {
+	Id     string            `json:"id"`
+	Tags   []string          `json:"tags"`
+	Labels map[string]string `json:"labels"`
+}
+
+// dockerConfig is the part of a docker config.json that holds credentials, credential helpers are not supported
+type dockerConfig struct {
+	Auths map[string]struct {
+		Auth          string `json:"auth"`
+		IdentityToken string `json:"identitytoken"`
+	} `json:"auths"`
+}
+
+// LoadDockerConfig reads the credentials from a docker config.json. An empty path looks in $DOCKER_CONFIG and then
+// ~/.docker, where a missing file just means there are no credentials.
+func LoadDockerConfig(path string) ([]RegistryAuth, error) {
+	optional := path == ""
+	if optional {
+		directory := os.Getenv("DOCKER_CONFIG")
+		if directory == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, nil
+			}
+			directory = filepath.Join(home, ".docker")
+		}
+		path = filepath.Join(directory, "config.json")
+	}
+
+	content, err := os.ReadFile(path)
+	if optional && errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var config dockerConfig
+	err = json.Unmarshal(content, &config)
+	if err != nil {
+		return nil, fmt.Errorf("parsing docker config %s failed, %w", path, err)
+	}
+
+	auths := make([]RegistryAuth, 0, len(config.Auths))
+	for server, entry := range config.Auths {
+		auth := RegistryAuth{
+			Server:        server,
+			IdentityToken: entry.IdentityToken,
+		}
+
+		if entry.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+			if err != nil {
+				return nil, fmt.Errorf("the auth of %s in docker config %s is not valid base64, %w", server, path, err)
+			}
+
+			auth.Username, auth.Password, _ = strings.Cut(string(decoded), ":")
+		}
+
+		auths = append(auths, auth)
+	}
+
+	return auths, nil
+}
+
+// registryHost normalizes a registry address, which may come with a scheme and a path, down to its host
+func registryHost(server string) string {
+	server = strings.TrimPrefix(server, "https://")
+	server = strings.TrimPrefix(server, "http://")
+	server, _, _ = strings.Cut(server, "/")
+
+	// docker hub goes by several names
+	if server == "index.docker.io" || server == "registry-1.docker.io" {
+		return "docker.io"
+	}
+
+	return server
+}
+
+// registryAuth returns the encoded credentials for the registry of the image, or an empty string if there are none
+func (c *implementation) registryAuth(imageName string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(imageName)
+	if err != nil {
+		return "", err
+	}
+
+	host := reference.Domain(named)
+	for _, auth := range c.auths {
+		if registryHost(auth.Server) != host {
+			continue
+		}
+
+		return registry.EncodeAuthConfig(registry.AuthConfig{
+			Username:      auth.Username,
+			Password:      auth.Password,
+			IdentityToken: auth.IdentityToken,
+			ServerAddress: auth.Server,
+		})
+	}
+
+	return "", nil
+}
+
+// Pull makes sure the image is present as the policy demands
+func (c *implementation) Pull(ctx context.Context, imageName string, policy PullPolicy) error {
+	if policy != PullAlways {
+		_, _, err := c.client.ImageInspectWithRaw(ctx, imageName)
+		if err == nil {
+			return nil
+		}
+
+		if !errdefs.IsNotFound(err) {
+			return err
+		}
+
+		if policy == PullNever {
+			return fmt.Errorf("image %s is not present and its pull policy is %s", imageName, PullNever)
+		}
+	}
+
+	registryAuth, err := c.registryAuth(imageName)
+	if err != nil {
+		return err
+	}
+
+	logger := log.WithField("image", imageName)
+	logger.Info("pulling image")
+
+	reader, err := c.client.ImagePull(ctx, imageName, image.PullOptions{RegistryAuth: registryAuth})
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	err = logPullProgress(logger, reader)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("pulled image")
+	return nil
+}
+
+// logPullProgress turns the json progress stream of a pull into log entries. Only changes of the status of a layer
+// are logged, the byte counts in between would flood the log.
+func logPullProgress(logger *log.Entry, reader io.Reader) error {
+	statuses := map[string]string{}
+
+	decoder := json.NewDecoder(reader)
+	for {
+		var message jsonmessage.JSONMessage
+		err := decoder.Decode(&message)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if message.Error != nil {
+			return message.Error
+		}
+
+		if statuses[message.ID] == message.Status {
+			continue
+		}
+		statuses[message.ID] = message.Status
+
+		entry := logger.WithField("status", message.Status)
+		if message.ID != "" {
+			entry = entry.WithField("layer", message.ID)
+		}
+		if message.Progress != nil && message.Progress.Total > 0 {
+			entry = entry.WithField("bytes", message.Progress.Total)
+		}
+		entry.Debug("image pull progress")
+	}
+}
+
+// Build builds an image with the classic builder and logs its output
+func (c *implementation) Build(ctx context.Context, options *BuildOptions) error {
+	buildArgs := map[string]*string{}
+	for key, value := range options.BuildArgs {
+		buildArgs[key] = &value
+	}
+
+	logger := log.WithField("tags", options.Tags)
+	logger.Info("building image")
+
+	buildResponse, err := c.client.ImageBuild(ctx, options.Context, types.ImageBuildOptions{
+		Tags:       options.Tags,
+		Dockerfile: options.Dockerfile,
+		BuildArgs:  buildArgs,
+		Labels:     options.Labels,
+		Remove:     true,
+	})
+	if err != nil {
+		return err
+	}
+	defer buildResponse.Body.Close()
+
+	decoder := json.NewDecoder(buildResponse.Body)
+	for {
+		var message jsonmessage.JSONMessage
+		err = decoder.Decode(&message)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if message.Error != nil {
+			return message.Error
+		}
+
+		line := strings.TrimSpace(message.Stream)
+		if line != "" {
+			logger.WithField("stream", "build").Debug(line)
+		}
+	}
+
+	logger.Info("built image")
+	return nil
+}
+
+// InspectImage returns nil when the image is not present
+func (c *implementation) InspectImage(ctx context.Context, imageName string) (*ImageInfo, error) {
+	inspectResponse, _, err := c.client.ImageInspectWithRaw(ctx, imageName)
+	if errdefs.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	info := &ImageInfo{
+		Id:   inspectResponse.ID,
+		Tags: inspectResponse.RepoTags,
+	}
+
+	if inspectResponse.Config != nil {
+		info.Labels = inspectResponse.Config.Labels
+	}
+
+	return info, nil
+}
@@ -0,0 +1,80 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/errdefs"
+)
+
+type NetworkOptions struct {
+	Name string `json:"name"`
+	// Internal networks have no route out, their containers only reach each other
+	Internal bool              `json:"internal"`
+	Labels   map[string]string `json:"labels"`
+}
+
+type NetworkInfo struct {
+	Id        string            `json:"id"`
+	Name      string            `json:"name"`
+	Labels    map[string]string `json:"labels"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
+
+func (c *implementation) CreateNetwork(ctx context.Context, options *NetworkOptions) (string, error) {
+	createResponse, err := c.client.NetworkCreate(ctx, options.Name, types.NetworkCreate{
+		Driver:   "bridge",
+		Internal: options.Internal,
+		Labels:   options.Labels,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return createResponse.ID, nil
+}
+
+// RemoveNetwork removes a network, which fails while containers are still attached to it. Removing a network that is
+// already gone is not an error.
+func (c *implementation) RemoveNetwork(ctx context.Context, networkId string) error {
+	err := c.client.NetworkRemove(ctx, networkId)
+	if errdefs.IsNotFound(err) {
+		return nil
+	}
+
+	return err
+}
+
+func (c *implementation) ConnectNetwork(ctx context.Context, networkId string, containerId string, aliases []string) error {
+	return c.client.NetworkConnect(ctx, networkId, containerId, &network.EndpointSettings{
+		Aliases: aliases,
+	})
+}
+
+func (c *implementation) ListNetworks(ctx context.Context, labels map[string]string) ([]NetworkInfo, error) {
+	labelFilters := filters.NewArgs()
+	for key, value := range labels {
+		labelFilters.Add("label", fmt.Sprintf("%s=%s", key, value))
+	}
+
+	resources, err := c.client.NetworkList(ctx, types.NetworkListOptions{Filters: labelFilters})
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]NetworkInfo, 0, len(resources))
+	for _, resource := range resources {
+		infos = append(infos, NetworkInfo{
+			Id:        resource.ID,
+			Name:      resource.Name,
+			Labels:    resource.Labels,
+			CreatedAt: resource.Created,
+		})
+	}
+
+	return infos, nil
+}
@@ -0,0 +1,53 @@
+package container
+
+import (
+	"context"
+	"maps"
+
+	"mirasynth.stream/github-runner/internal/atlas"
+)
+
+// Sidecar is a container that lives and dies with the container it belongs to, such as a docker daemon or a proxy
+type Sidecar struct {
+	Options
+	// Aliases are the names the container reaches the sidecar by on their network
+	Aliases []string `json:"aliases"`
+	// Bridged sidecars start on the default bridge and join the network of the container on top, which gives them a
+	// way out of an internal network
+	Bridged bool `json:"bridged"`
+}
+
+// createSidecar creates the sidecar on the network of the container it belongs to, and labels it with the id of
+// that container
+func (c *implementation) createSidecar(ctx context.Context, containerId string, network string, sidecar *Sidecar) error {
+	options := sidecar.Options
+	options.Labels = maps.Clone(options.Labels)
+	if options.Labels == nil {
+		options.Labels = map[string]string{}
+	}
+	options.Labels[atlas.CONTAINER_LABEL_SIDECAR] = containerId
+
+	if sidecar.Bridged {
+		options.Network = ""
+	} else {
+		options.Network = network
+		options.NetworkAliases = sidecar.Aliases
+	}
+
+	sidecarId, err := c.create(ctx, &options)
+	if err != nil {
+		return err
+	}
+
+	if !sidecar.Bridged || network == "" {
+		return nil
+	}
+
+	return c.ConnectNetwork(ctx, network, sidecarId, sidecar.Aliases)
+}
+
+func (c *implementation) listSidecars(ctx context.Context, containerId string) ([]Info, error) {
+	return c.List(ctx, map[string]string{
+		atlas.CONTAINER_LABEL_SIDECAR: containerId,
+	})
+}
@@ -0,0 +1,250 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/errdefs"
+	"mirasynth.stream/github-runner/internal/atlas"
+)
+
+// Mount mounts a named volume into the container
+type Mount struct {
+	// Volume is the name of the volume, it is created when it does not exist yet
+	Volume   string `json:"volume"`
+	Path     string `json:"path"`
+	ReadOnly bool   `json:"readOnly"`
+	// CopyOnWrite mounts an overlay of the volume, the writes land in a snapshot that goes away with the container
+	CopyOnWrite bool `json:"copyOnWrite"`
+	// Labels are put on the volume when it gets created
+	Labels map[string]string `json:"labels"`
+}
+
+// VolumeInfo describes a volume, Size and RefCount are -1 when the daemon does not know them
+type VolumeInfo struct {
+	Name      string            `json:"name"`
+	Labels    map[string]string `json:"labels"`
+	Size      int64             `json:"size"`
+	RefCount  int64             `json:"refCount"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
+
+// mounts creates the volumes the container mounts and, for copy-on-write mounts, their snapshots
+func (c *implementation) mounts(ctx context.Context, options *Options) ([]mount.Mount, error) {
+	mounts := make([]mount.Mount, 0, len(options.Mounts))
+	for i := range options.Mounts {
+		m := &options.Mounts[i]
+
+		err := c.ensureVolume(ctx, options.ImageName, m)
+		if err != nil {
+			return nil, err
+		}
+
+		if !m.CopyOnWrite {
+			mounts = append(mounts, mount.Mount{
+				Type:     mount.TypeVolume,
+				Source:   m.Volume,
+				Target:   m.Path,
+				ReadOnly: m.ReadOnly,
+			})
+			continue
+		}
+
+		snapshot, err := c.snapshot(ctx, options, m)
+		if err != nil {
+			return nil, err
+		}
+
+		mounts = append(mounts, snapshot)
+	}
+
+	return mounts, nil
+}
+
+// ensureVolume creates the volume when it does not exist yet. A fresh volume is owned by root, so it is opened up to
+// whichever user the image runs as.
+func (c *implementation) ensureVolume(ctx context.Context, imageName string, m *Mount) error {
+	_, err := c.client.VolumeInspect(ctx, m.Volume)
+	if err == nil || !errdefs.IsNotFound(err) {
+		return err
+	}
+
+	_, err = c.client.VolumeCreate(ctx, volume.CreateOptions{Name: m.Volume, Labels: m.Labels})
+	if err != nil {
+		return err
+	}
+
+	return c.prepareVolume(ctx, imageName, m.Volume, "chmod 1777 /volume")
+}
+
+// prepareVolume runs a shell script as root with the volume mounted on /volume
+func (c *implementation) prepareVolume(ctx context.Context, imageName string, volumeName string, script string) error {
+	exitCode, err := c.run(ctx, &Options{
+		ImageName:  imageName,
+		User:       "0",
+		Entrypoint: []string{"sh", "-c", script},
+		Mounts:     []Mount{{Volume: volumeName, Path: "/volume"}},
+	})
+	if err != nil {
+		return err
+	}
+
+	if exitCode != 0 {
+		return fmt.Errorf("preparing volume %s exited with %d", volumeName, exitCode)
+	}
+
+	return nil
+}
+
+// snapshot mounts an overlay with the volume as its lower dir. The upper and work dirs live in a snapshot volume
+// labelled with the name of the container, the overlay itself is an anonymous volume that goes with the container.
+// The mountpoints are paths on the host of the daemon, so this only works with local volumes on a local daemon, and
+// never while a job writes to the lower dir through a plain mount, which overlayfs leaves undefined.
+func (c *implementation) snapshot(ctx context.Context, options *Options, m *Mount) (mount.Mount, error) {
+	labels := maps.Clone(options.Labels)
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[atlas.VOLUME_LABEL_SNAPSHOT] = options.Name
+	// the overlay only refers to the lower volume by its path, so docker does not count it as being in use
+	labels[atlas.VOLUME_LABEL_LOWER] = m.Volume
+
+	snapshot, err := c.client.VolumeCreate(ctx, volume.CreateOptions{
+		Name:   fmt.Sprintf("%s-%s", options.Name, m.Volume),
+		Labels: labels,
+	})
+	if err != nil {
+		return mount.Mount{}, err
+	}
+
+	lower, err := c.client.VolumeInspect(ctx, m.Volume)
+	if err != nil {
+		return mount.Mount{}, err
+	}
+
+	if lower.Driver != "local" {
+		return mount.Mount{}, fmt.Errorf("volume %s uses the %s driver, copy on write needs a local volume", m.Volume, lower.Driver)
+	}
+
+	// overlayfs wants both dirs to exist, and the upper dir decides who may write to the root of the mount
+	err = c.prepareVolume(ctx, options.ImageName, snapshot.Name, "mkdir -p /volume/upper /volume/work && chmod 1777 /volume/upper")
+	if err != nil {
+		return mount.Mount{}, err
+	}
+
+	return mount.Mount{
+		Type:   mount.TypeVolume,
+		Target: m.Path,
+		VolumeOptions: &mount.VolumeOptions{
+			DriverConfig: &mount.Driver{
+				Name: "local",
+				Options: map[string]string{
+					"type":   "overlay",
+					"device": "overlay",
+					"o": fmt.Sprintf("lowerdir=%s,upperdir=%s/upper,workdir=%s/work",
+						lower.Mountpoint, snapshot.Mountpoint, snapshot.Mountpoint),
+				},
+			},
+		},
+	}, nil
+}
+
+// run runs a short lived container to completion and removes it again
+func (c *implementation) run(ctx context.Context, options *Options) (int64, error) {
+	containerId, err := c.create(ctx, options)
+	if err != nil {
+		return 0, err
+	}
+	defer c.remove(context.Background(), containerId)
+
+	err = c.client.ContainerStart(ctx, containerId, containerStartOptions())
+	if err != nil {
+		return 0, err
+	}
+
+	return c.Wait(ctx, containerId)
+}
+
+// removeSnapshots removes the snapshot volumes of a container once the container itself is gone
+func (c *implementation) removeSnapshots(ctx context.Context, containerName string) error {
+	volumes, err := c.ListVolumes(ctx, map[string]string{
+		atlas.VOLUME_LABEL_SNAPSHOT: containerName,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, v := range volumes {
+		err = c.RemoveVolume(ctx, v.Name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ListVolumes asks the daemon for the disk usage, which is the only place it reports the size of volumes
+func (c *implementation) ListVolumes(ctx context.Context, labels map[string]string) ([]VolumeInfo, error) {
+	labelFilters := filters.NewArgs()
+	for key, value := range labels {
+		labelFilters.Add("label", fmt.Sprintf("%s=%s", key, value))
+	}
+
+	listResponse, err := c.client.VolumeList(ctx, volume.ListOptions{Filters: labelFilters})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(listResponse.Volumes) == 0 {
+		return nil, nil
+	}
+
+	diskUsage, err := c.client.DiskUsage(ctx, types.DiskUsageOptions{Types: []types.DiskUsageObject{types.VolumeObject}})
+	if err != nil {
+		return nil, err
+	}
+
+	usage := map[string]*volume.UsageData{}
+	for _, v := range diskUsage.Volumes {
+		usage[v.Name] = v.UsageData
+	}
+
+	infos := make([]VolumeInfo, 0, len(listResponse.Volumes))
+	for _, v := range listResponse.Volumes {
+		info := VolumeInfo{
+			Name:     v.Name,
+			Labels:   v.Labels,
+			Size:     -1,
+			RefCount: -1,
+		}
+
+		info.CreatedAt, _ = time.Parse(time.RFC3339, v.CreatedAt)
+
+		if usageData := usage[v.Name]; usageData != nil {
+			info.Size = usageData.Size
+			info.RefCount = usageData.RefCount
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+// RemoveVolume removes a volume, which fails while a container uses it. Removing a volume that is already gone is
+// not an error.
+func (c *implementation) RemoveVolume(ctx context.Context, name string) error {
+	err := c.client.VolumeRemove(ctx, name, false)
+	if errdefs.IsNotFound(err) {
+		return nil
+	}
+
+	return err
+}
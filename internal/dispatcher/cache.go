@@ -0,0 +1,41 @@
+package dispatcher
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"mirasynth.stream/github-runner/internal/atlas"
+	"mirasynth.stream/github-runner/internal/config"
+	"mirasynth.stream/github-runner/internal/container"
+)
+
+// CacheVolumeName returns the volume a cache of a pool lives in, every runner of the pool mounts the same one
+func CacheVolumeName(pool string, cache string) string {
+	return fmt.Sprintf("github-runner-cache-%s-%s", pool, cache)
+}
+
+// mounts maps the caches of the pool onto the volumes the runner container mounts
+func (d *implementation) mounts(pool *config.Pool) []container.Mount {
+	mounts := make([]container.Mount, 0, len(pool.Caches))
+	for _, cache := range pool.Caches {
+		mountPath := cache.Path
+		if strings.HasPrefix(mountPath, "~/") {
+			mountPath = path.Join(atlas.RUNNER_HOME, mountPath[2:])
+		}
+
+		mounts = append(mounts, container.Mount{
+			Volume:      CacheVolumeName(pool.Name, cache.Name),
+			Path:        mountPath,
+			ReadOnly:    cache.ReadOnly,
+			CopyOnWrite: cache.CopyOnWrite,
+			Labels: map[string]string{
+				atlas.CONTAINER_LABEL_INSTANCE: d.instanceId,
+				atlas.CONTAINER_LABEL_POOL:     pool.Name,
+				atlas.VOLUME_LABEL_CACHE:       cache.Name,
+			},
+		})
+	}
+
+	return mounts
+}
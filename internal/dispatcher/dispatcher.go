@@ -0,0 +1,237 @@
+package dispatcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"mirasynth.stream/github-runner/internal/config"
+	"mirasynth.stream/github-runner/internal/container"
+	"mirasynth.stream/github-runner/internal/github"
+	"mirasynth.stream/github-runner/internal/github/events"
+	"mirasynth.stream/github-runner/internal/store"
+)
+
+// defaultRunnerGroupId is the id of the Default runner group every repository and organization has
+const defaultRunnerGroupId = 1
+
+// ErrDuplicateJob is returned by Submit when the job, or the delivery it came from, has already been submitted
+var ErrDuplicateJob = errors.New("the job has already been submitted")
+
+type Job struct {
+	Id            int64    `json:"id"`
+	RunId         int64    `json:"runId"`
+	DeliveryId    string   `json:"deliveryId"`
+	Owner         string   `json:"owner"`
+	Repository    string   `json:"repository"`
+	RepositoryUrl string   `json:"repositoryUrl"`
+	Labels        []string `json:"labels"`
+	Pool          string   `json:"pool"`
+	// CreatedAt is when the job was queued
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// NewJobFromEvent maps a workflow_job event onto a Job, it returns nil for events without a repository
+func NewJobFromEvent(deliveryId string, event *events.WorkflowJobEvent) *Job {
+	if event.Repository == nil {
+		return nil
+	}
+
+	job := NewJobFromWorkflowJob(event.Repository, &event.WorkflowJob)
+	job.DeliveryId = deliveryId
+
+	return job
+}
+
+// NewJobFromWorkflowJob maps a workflow job, as listed by the api, onto a Job
+func NewJobFromWorkflowJob(repository *github.Repository, workflowJob *github.WorkflowJob) *Job {
+	return &Job{
+		Id:            workflowJob.Id,
+		RunId:         workflowJob.RunId,
+		Owner:         repository.Owner.Login,
+		Repository:    repository.Name,
+		RepositoryUrl: repository.HtmlUrl,
+		Labels:        workflowJob.Labels,
+		CreatedAt:     workflowJob.CreatedAt,
+	}
+}
+
+// MatchPool returns the pool whose runners can run the job, or nil for jobs that are not meant for self-hosted
+// runners or that no pool matches
+func MatchPool(job *Job) *config.Pool {
+	// jobs for GitHub hosted runners are also delivered to the app, only self-hosted jobs are ours to run
+	if !slices.Contains(job.Labels, "self-hosted") {
+		return nil
+	}
+
+	return config.Get().MatchPool(job.Labels)
+}
+
+// DeliveryKey returns the store key a submitted delivery is recorded under
+func DeliveryKey(deliveryId string) string {
+	return fmt.Sprintf("delivery:%s", deliveryId)
+}
+
+// JobKey returns the store key a submitted job is recorded under
+func JobKey(jobId int64) string {
+	return fmt.Sprintf("workflow_job:%d", jobId)
+}
+
+// RunnerKey returns the store key a runner started by this instance is recorded under, with when it was created as
+// the value. The record outlives the container until the registration of the runner is gone too, so the collector
+// can tell the runners of this instance from those of another one registered with the same repository.
+func RunnerKey(name string) string {
+	return fmt.Sprintf("runner:%s", name)
+}
+
+type Dispatcher interface {
+	// Submit deduplicates the job and dispatches it in the background. Webhooks, delivery recovery and anything else
+	// that learns about queued jobs should go through Submit.
+	Submit(*Job) error
+	Dispatch(context.Context, *Job) error
+	// Adopt takes over the runner containers a previous run of the daemon left behind. It is safe to call again at
+	// any time to bring the in-memory state back in line with the containers.
+	Adopt(context.Context) error
+	// Provision starts a warm runner for the pool that waits for a job, Retire scales an idle runner down again
+	Provision(context.Context, *config.Pool) error
+	Retire(context.Context, string) error
+	// Prepull pulls the images of every pool, so the first job of a pool does not wait on the pull
+	Prepull(context.Context)
+	Runners() []Runner
+}
+
+type implementation struct {
+	github     github.Client
+	container  container.Container
+	store      store.Store
+	instanceId string
+
+	mutex   sync.Mutex
+	runners map[string]*Runner
+}
+
+// New creates a dispatcher, the instance id marks the containers it owns and must survive restarts of the daemon
+func New(githubClient github.Client, containerClient container.Container, s store.Store, instanceId string) Dispatcher {
+	return &implementation{
+		github:     githubClient,
+		container:  containerClient,
+		store:      s,
+		instanceId: instanceId,
+		runners:    map[string]*Runner{},
+	}
+}
+
+func (d *implementation) Submit(job *Job) error {
+	if !slices.Contains(job.Labels, "self-hosted") {
+		return nil
+	}
+
+	pool := MatchPool(job)
+	if pool == nil {
+		log.WithFields(log.Fields{
+			"job":    job.Id,
+			"labels": job.Labels,
+		}).Warn("no pool matches the labels of the job")
+		return nil
+	}
+
+	job.Pool = pool.Name
+
+	// redeliveries keep their delivery id, but a job can also arrive through a different delivery
+	deduplicationKeys := []string{JobKey(job.Id)}
+	if job.DeliveryId != "" {
+		deduplicationKeys = append(deduplicationKeys, DeliveryKey(job.DeliveryId))
+	}
+
+	claimed, err := d.store.Claim(config.Get().GitHub.Webhook.DeduplicationTtl, deduplicationKeys...)
+	if err != nil {
+		return err
+	}
+
+	if !claimed {
+		return ErrDuplicateJob
+	}
+
+	if r := d.claimWarmRunner(job); r != nil {
+		r.logger().Info("job goes to a warm runner")
+		return nil
+	}
+
+	// github gives up on a delivery after 10 seconds, which an image pull can easily exceed
+	go func() {
+		err := d.Dispatch(context.Background(), job)
+		if err == nil {
+			return
+		}
+
+		log.WithFields(log.Fields{
+			"delivery": job.DeliveryId,
+			"job":      job.Id,
+		}).Error(err)
+
+		// forget the job so a redelivery gets another chance at starting a runner
+		err = d.store.Release(deduplicationKeys...)
+		if err != nil {
+			log.WithField("job", job.Id).Error(err)
+		}
+	}()
+
+	return nil
+}
+
+// Dispatch provisions a runner container from the pool of a queued workflow job. The container is started in the
+// background, so Dispatch returns as soon as the container has been created.
+func (d *implementation) Dispatch(ctx context.Context, job *Job) error {
+	pool := config.Get().GetPool(job.Pool)
+	if pool == nil {
+		return fmt.Errorf("pool %q does not exist", job.Pool)
+	}
+
+	r := &Runner{
+		Name:       newRunnerName(),
+		Pool:       pool.Name,
+		JobId:      job.Id,
+		Scope:      pool.Scope,
+		Owner:      job.Owner,
+		Repository: job.Repository,
+		CreatedAt:  time.Now(),
+	}
+
+	if !d.reserve(pool, r) {
+		return fmt.Errorf("pool %q is at its maximum size of %d runners", pool.Name, pool.MaxSize)
+	}
+
+	// recorded before the runner is registered, so it is never registered without the collector knowing it is ours
+	err := d.store.Set(RunnerKey(r.Name), r.CreatedAt.Format(time.RFC3339), 0)
+	if err != nil {
+		d.forget(r)
+		return err
+	}
+
+	containerId, err := d.create(ctx, pool, r)
+	if err != nil {
+		cleanupErr := d.cleanup(context.Background(), r)
+		d.forget(r)
+		return errors.Join(err, cleanupErr)
+	}
+
+	r.ContainerId = containerId
+
+	logger := r.logger()
+	logger.Info("starting runner container")
+
+	err = d.container.Start(ctx, containerId)
+	if err != nil {
+		cleanupErr := d.cleanup(context.Background(), r)
+		d.forget(r)
+		return errors.Join(err, cleanupErr)
+	}
+
+	go d.watch(r)
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package dispatcher
+
+import (
+	"context"
+	"fmt"
+
+	"mirasynth.stream/github-runner/internal/atlas"
+	"mirasynth.stream/github-runner/internal/config"
+	"mirasynth.stream/github-runner/internal/container"
+)
+
+// createNetwork creates the network of the runner when its pool isolates runners, it returns the network the runner
+// container starts on, which is empty for the default bridge
+func (d *implementation) createNetwork(ctx context.Context, pool *config.Pool, r *Runner) (string, error) {
+	if pool.Network.Mode != config.NetworkModeIsolated {
+		return "", nil
+	}
+
+	_, err := d.container.CreateNetwork(ctx, &container.NetworkOptions{
+		Name: r.Name,
+		// with the egress sidecar in place the proxy is the only way out
+		Internal: pool.Network.Egress.Mode == config.EgressModeSidecar,
+		Labels:   r.labels(d.instanceId),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return r.Name, nil
+}
+
+// removeNetwork removes the network of the runner, which has to wait until the runner and its sidecars are gone.
+// Runners on the default bridge have no network of their own, removing it is a no-op then.
+func (d *implementation) removeNetwork(ctx context.Context, r *Runner) error {
+	return d.container.RemoveNetwork(ctx, r.Name)
+}
+
+// proxyUrl returns the proxy the runner and its sidecars send their requests through, or an empty string when
+// egress is unrestricted
+func proxyUrl(pool *config.Pool) string {
+	switch pool.Network.Egress.Mode {
+	case config.EgressModeSidecar:
+		return fmt.Sprintf("http://%s:%d", atlas.EGRESS_PROXY_ALIAS, atlas.EGRESS_PROXY_PORT)
+	case config.EgressModeEnv:
+		return pool.Network.Egress.Proxy
+	}
+
+	return ""
+}
+
+// proxyEnvironment points both spellings of the proxy variables at the proxy, tools disagree on which one they read
+func proxyEnvironment(pool *config.Pool) []string {
+	proxy := proxyUrl(pool)
+	if proxy == "" {
+		return nil
+	}
+
+	// the docker and buildkit sidecars are reached directly on the runner network
+	noProxy := fmt.Sprintf("localhost,127.0.0.1,%s,%s", sidecarDocker, sidecarBuildkit)
+
+	return []string{
+		fmt.Sprintf("HTTP_PROXY=%s", proxy),
+		fmt.Sprintf("HTTPS_PROXY=%s", proxy),
+		fmt.Sprintf("NO_PROXY=%s", noProxy),
+		fmt.Sprintf("http_proxy=%s", proxy),
+		fmt.Sprintf("https_proxy=%s", proxy),
+		fmt.Sprintf("no_proxy=%s", noProxy),
+	}
+}
@@ -0,0 +1,89 @@
+package dispatcher
+
+import (
+	"fmt"
+	"strings"
+
+	"mirasynth.stream/github-runner/internal/config"
+	"mirasynth.stream/github-runner/internal/github"
+)
+
+// Registration is the organization or repository a runner registers with
+type Registration struct {
+	Scope      config.PoolScope
+	Owner      string
+	Repository string
+}
+
+// NewOrganizationRegistration returns the registration of the runners of an organization scoped pool
+func NewOrganizationRegistration(organization string) Registration {
+	return Registration{
+		Scope: config.PoolScopeOrganization,
+		Owner: organization,
+	}
+}
+
+// NewRepositoryRegistration returns the registration of the runners of a repository scoped pool
+func NewRepositoryRegistration(owner string, repository string) Registration {
+	return Registration{
+		Scope:      config.PoolScopeRepository,
+		Owner:      owner,
+		Repository: repository,
+	}
+}
+
+// String returns the organization, or the owner/name of the repository
+func (g Registration) String() string {
+	if g.Scope == config.PoolScopeOrganization {
+		return g.Owner
+	}
+
+	return fmt.Sprintf("%s/%s", g.Owner, g.Repository)
+}
+
+// Url returns the url config.sh registers a runner with
+func (g Registration) Url() string {
+	return fmt.Sprintf("%s/%s", strings.TrimSuffix(config.Get().GitHub.WebUrl, "/"), g.String())
+}
+
+// ListRunners lists the runners registered with the organization or repository
+func (g Registration) ListRunners(githubClient github.Client) ([]github.Runner, error) {
+	if g.Scope == config.PoolScopeOrganization {
+		response, err := githubClient.ListSelfHostedRunnersForOrganization(&github.ListSelfHostedRunnersForOrganizationOptions{
+			Organization: g.Owner,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return response.Runners, nil
+	}
+
+	response, err := githubClient.ListSelfHostedRunnersForRepository(&github.LListSelfHostedRunnersForRepositoryOptions{
+		Username:   g.Owner,
+		Repository: g.Repository,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Runners, nil
+}
+
+// DeleteRunner removes a runner registration from the organization or repository
+func (g Registration) DeleteRunner(githubClient github.Client, runnerId int) error {
+	if g.Scope == config.PoolScopeOrganization {
+		_, err := githubClient.DeleteSelfHostedRunnerFromOrganization(&github.DeleteSelfHostedRunnerFromOrganizationOptions{
+			Organization: g.Owner,
+			RunnerId:     runnerId,
+		})
+		return err
+	}
+
+	_, err := githubClient.DeleteSelfHostedRunnerFromRepository(&github.DeleteSelfHostedRunnerFromRepositoryOptions{
+		Username:   g.Owner,
+		Repository: g.Repository,
+		RunnerId:   runnerId,
+	})
+	return err
+}
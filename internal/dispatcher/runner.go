@@ -0,0 +1,557 @@
+package dispatcher
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"mirasynth.stream/github-runner/internal/atlas"
+	"mirasynth.stream/github-runner/internal/config"
+	"mirasynth.stream/github-runner/internal/container"
+	"mirasynth.stream/github-runner/internal/github"
+	"mirasynth.stream/github-runner/internal/runnergroup"
+	"mirasynth.stream/github-runner/internal/runnerimage"
+)
+
+var runnerNamePattern = regexp.MustCompile(`^runner-[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+func newRunnerName() string {
+	return fmt.Sprintf("runner-%s", uuid.NewString())
+}
+
+// IsRunnerName reports whether a runner registered on GitHub was named by a dispatcher
+func IsRunnerName(name string) bool {
+	return runnerNamePattern.MatchString(name)
+}
+
+// Runner is a runner container the dispatcher started, or adopted after a restart
+type Runner struct {
+	Name        string `json:"name"`
+	ContainerId string `json:"containerId"`
+	Pool        string `json:"pool"`
+	// JobId is 0 for a warm runner that has not been handed a job yet
+	JobId int64            `json:"jobId"`
+	Scope config.PoolScope `json:"scope"`
+	Owner string           `json:"owner"`
+	// Repository is empty for a warm runner of an organization scoped pool
+	Repository string    `json:"repository"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// Registration returns the organization or repository the runner is registered with
+func (r *Runner) Registration() Registration {
+	if r.Scope == config.PoolScopeOrganization {
+		return NewOrganizationRegistration(r.Owner)
+	}
+
+	return NewRepositoryRegistration(r.Owner, r.Repository)
+}
+
+// fullName returns the owner/name of the repository the runner was started for, or only the owner when it has none
+func (r *Runner) fullName() string {
+	if r.Repository == "" {
+		return r.Owner
+	}
+
+	return fmt.Sprintf("%s/%s", r.Owner, r.Repository)
+}
+
+func (r *Runner) logger() *log.Entry {
+	return log.WithFields(log.Fields{
+		"job":        r.JobId,
+		"pool":       r.Pool,
+		"repository": r.fullName(),
+		"runner":     r.Name,
+		"container":  r.ContainerId,
+	})
+}
+
+// labels returns the labels that tie a container to the runner and to the daemon instance that owns it
+func (r *Runner) labels(instanceId string) map[string]string {
+	return map[string]string{
+		atlas.CONTAINER_LABEL_INSTANCE:   instanceId,
+		atlas.CONTAINER_LABEL_POOL:       r.Pool,
+		atlas.CONTAINER_LABEL_REPOSITORY: r.fullName(),
+		atlas.CONTAINER_LABEL_SCOPE:      string(r.Scope),
+		atlas.CONTAINER_LABEL_JOB:        strconv.FormatInt(r.JobId, 10),
+		atlas.CONTAINER_LABEL_RUNNER:     r.Name,
+	}
+}
+
+// NewRunnerFromContainer rebuilds a runner from the labels of its container
+func NewRunnerFromContainer(info *container.Info) *Runner {
+	owner, repository, _ := strings.Cut(info.Labels[atlas.CONTAINER_LABEL_REPOSITORY], "/")
+	jobId, _ := strconv.ParseInt(info.Labels[atlas.CONTAINER_LABEL_JOB], 10, 64)
+
+	// containers from before organization scoped pools carry no scope label
+	scope := config.PoolScope(info.Labels[atlas.CONTAINER_LABEL_SCOPE])
+	if scope == "" {
+		scope = config.PoolScopeRepository
+	}
+
+	return &Runner{
+		Name:        info.Labels[atlas.CONTAINER_LABEL_RUNNER],
+		ContainerId: info.Id,
+		Pool:        info.Labels[atlas.CONTAINER_LABEL_POOL],
+		JobId:       jobId,
+		Scope:       scope,
+		Owner:       owner,
+		Repository:  repository,
+		CreatedAt:   info.CreatedAt,
+	}
+}
+
+func (d *implementation) Runners() []Runner {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	runners := make([]Runner, 0, len(d.runners))
+	for _, r := range d.runners {
+		runners = append(runners, *r)
+	}
+
+	return runners
+}
+
+// Adopt lists the containers labelled with the instance id and takes them back into the in-memory state. Runners
+// that are still running are watched again, the ones that exited without being noticed are cleaned up and the ones
+// whose container vanished are forgotten.
+func (d *implementation) Adopt(ctx context.Context) error {
+	infos, err := d.container.List(ctx, map[string]string{
+		atlas.CONTAINER_LABEL_INSTANCE: d.instanceId,
+	})
+	if err != nil {
+		return err
+	}
+
+	containers := map[string]bool{}
+	for _, info := range infos {
+		// sidecars go along with their runner
+		if info.Labels[atlas.CONTAINER_LABEL_SIDECAR] != "" {
+			continue
+		}
+
+		r := NewRunnerFromContainer(&info)
+		if r.JobId == 0 {
+			r.JobId = d.claimedJob(r)
+		}
+		logger := r.logger()
+		containers[r.Name] = true
+
+		d.mutex.Lock()
+		_, known := d.runners[r.Name]
+		if info.Running && !known {
+			d.runners[r.Name] = r
+		}
+		d.mutex.Unlock()
+
+		// a known runner is being watched already, and cleaned up by its watch once it exits
+		if known {
+			continue
+		}
+
+		if !info.Running {
+			logger.Info("cleaning up runner container that exited unnoticed")
+			err = d.cleanup(ctx, r)
+			if err != nil {
+				logger.Error(err)
+			}
+			continue
+		}
+
+		// containers from before runners were recorded in the store get their record now
+		if _, ok := d.store.Get(RunnerKey(r.Name)); !ok {
+			err = d.store.Set(RunnerKey(r.Name), r.CreatedAt.Format(time.RFC3339), 0)
+			if err != nil {
+				logger.Error(err)
+			}
+		}
+
+		logger.Info("adopted running runner container")
+		go d.watch(r)
+	}
+
+	d.mutex.Lock()
+	var vanished []*Runner
+	for name, r := range d.runners {
+		if !containers[name] {
+			vanished = append(vanished, r)
+		}
+	}
+	d.mutex.Unlock()
+
+	// a runner may have gotten its container after the list, so it is looked up once more on its own
+	for _, r := range vanished {
+		infos, err = d.container.List(ctx, map[string]string{
+			atlas.CONTAINER_LABEL_INSTANCE: d.instanceId,
+			atlas.CONTAINER_LABEL_RUNNER:   r.Name,
+		})
+		if err != nil {
+			return err
+		}
+
+		if len(infos) == 0 && r.ContainerId != "" {
+			r.logger().Warn("forgetting runner whose container vanished")
+			d.forget(r)
+		}
+	}
+
+	return nil
+}
+
+// Prepull also warns about pool images that are older than the minimum runner version
+func (d *implementation) Prepull(ctx context.Context) {
+	for _, pool := range config.Get().Pools {
+		for _, imageName := range poolImages(&pool) {
+			logger := log.WithFields(log.Fields{
+				"pool":  pool.Name,
+				"image": imageName,
+			})
+
+			err := d.container.Pull(ctx, imageName, container.PullPolicy(pool.PullPolicy))
+			if err != nil {
+				logger.Error(err)
+			}
+		}
+
+		_, err := runnerimage.CheckVersion(ctx, d.container, pool.Image)
+		if err != nil {
+			log.WithField("pool", pool.Name).Error(err)
+		}
+	}
+}
+
+// watch waits for the runner container to exit, which an ephemeral runner does once it has run its job, and then
+// cleans up after it
+func (d *implementation) watch(r *Runner) {
+	defer d.forget(r)
+
+	ctx := context.Background()
+	logger := r.logger()
+
+	exitCode, err := d.container.Wait(ctx, r.ContainerId)
+	if err != nil {
+		logger.Error(err)
+	}
+
+	if exitCode != 0 {
+		logger.WithField("exitCode", exitCode).Warn("runner container exited with an error")
+		d.logOutput(ctx, logger, r.ContainerId)
+	} else {
+		logger.Info("runner container exited, cleaning up")
+	}
+
+	err = d.cleanup(ctx, r)
+	if err != nil {
+		logger.Error(err)
+	}
+}
+
+// logOutput copies what the container wrote into the log, so a failing runner can be diagnosed after its container
+// has been removed
+func (d *implementation) logOutput(ctx context.Context, logger *log.Entry, containerId string) {
+	logs, err := d.container.Logs(ctx, containerId, false)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+	defer logs.Close()
+
+	scanner := bufio.NewScanner(logs)
+	for scanner.Scan() {
+		logger.WithField("stream", "container").Info(scanner.Text())
+	}
+}
+
+// cleanup removes the container of a runner that has exited, together with its registration in case the runner
+// exited without running a job and so never unregistered itself
+func (d *implementation) cleanup(ctx context.Context, r *Runner) error {
+	// a runner that failed to start may not have gotten as far as its container
+	if r.ContainerId != "" {
+		err := d.container.Remove(ctx, r.ContainerId)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := d.removeNetwork(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	err = d.deregister(r)
+	if err != nil {
+		return err
+	}
+
+	return d.store.Release(RunnerKey(r.Name), claimKey(r.Name))
+}
+
+// claimedJob returns the job a warm runner claimed before a restart, or 0 when it is still idle
+func (d *implementation) claimedJob(r *Runner) int64 {
+	value, ok := d.store.Get(claimKey(r.Name))
+	if !ok {
+		return 0
+	}
+
+	jobId, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return jobId
+}
+
+// deregister deletes the registration of the runner, if it still has one
+func (d *implementation) deregister(r *Runner) error {
+	registration := r.Registration()
+	runners, err := registration.ListRunners(d.github)
+	if err != nil {
+		return err
+	}
+
+	for _, runner := range runners {
+		if runner.Name != r.Name {
+			continue
+		}
+
+		return registration.DeleteRunner(d.github, runner.Id)
+	}
+
+	return nil
+}
+
+func (d *implementation) create(ctx context.Context, pool *config.Pool, r *Runner) (string, error) {
+	registrationEnvironment, err := d.registrationEnvironment(pool, r)
+	if err != nil {
+		return "", err
+	}
+
+	resources, err := newResources(&pool.Resources)
+	if err != nil {
+		return "", err
+	}
+
+	network, err := d.createNetwork(ctx, pool, r)
+	if err != nil {
+		return "", err
+	}
+
+	sidecars, sidecarEnvironment := d.sidecars(pool, r)
+
+	// the pool environment goes first, so it can never override how the runner registers or reaches its sidecars
+	environment := slices.Concat(pool.Environment, sidecarEnvironment, registrationEnvironment)
+
+	return d.container.Create(ctx, &container.Options{
+		Name:        r.Name,
+		ImageName:   pool.Image,
+		Environment: environment,
+		Labels:      r.labels(d.instanceId),
+		Resources:   resources,
+		Security:    newSecurity(&pool.Security),
+		PullPolicy:  container.PullPolicy(pool.PullPolicy),
+		Network:     network,
+		Sidecars:    sidecars,
+		Mounts:      d.mounts(pool),
+	})
+}
+
+// registrationEnvironment returns the environment the runner image needs to register itself. Just-in-time runners
+// only get their encoded config, which is useless once the runner has been used, instead of a registration token.
+func (d *implementation) registrationEnvironment(pool *config.Pool, r *Runner) ([]string, error) {
+	registration := r.Registration()
+
+	if pool.Registration == config.PoolRegistrationJit {
+		runnerGroupId := defaultRunnerGroupId
+		if pool.RunnerGroup != "" {
+			var err error
+			runnerGroupId, err = runnergroup.Id(d.github, registration.Owner, pool.RunnerGroup)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		jitConfig, err := d.generateJitConfig(registration, &github.GenerateJitConfigRequest{
+			Name:          r.Name,
+			RunnerGroupId: runnerGroupId,
+			Labels:        pool.RunnerLabels(),
+			WorkFolder:    "_work",
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return []string{
+			fmt.Sprintf("GITHUB_RUNNER_JITCONFIG=%s", jitConfig),
+		}, nil
+	}
+
+	registrationToken, err := d.registrationToken(registration)
+	if err != nil {
+		return nil, err
+	}
+
+	environment := []string{
+		fmt.Sprintf("GITHUB_RUNNER_REPOSITORY=%s", registration.Url()),
+		fmt.Sprintf("GITHUB_RUNNER_TOKEN=%s", registrationToken),
+		fmt.Sprintf("GITHUB_RUNNER_LABELS=%s", strings.Join(pool.Labels, ",")),
+		fmt.Sprintf("GITHUB_RUNNER_NAME=%s", r.Name),
+	}
+
+	if pool.RunnerGroup != "" {
+		environment = append(environment, fmt.Sprintf("GITHUB_RUNNER_GROUP=%s", pool.RunnerGroup))
+	}
+
+	return environment, nil
+}
+
+// generateJitConfig registers a just-in-time runner with the organization or repository and returns its encoded config
+func (d *implementation) generateJitConfig(registration Registration, request *github.GenerateJitConfigRequest) (string, error) {
+	if registration.Scope == config.PoolScopeOrganization {
+		jitConfig, err := d.github.GenerateJitConfigForOrganization(&github.GenerateJitConfigForOrganizationOptions{
+			Organization: registration.Owner,
+			RequestData:  request,
+		})
+		if err != nil {
+			return "", err
+		}
+
+		return jitConfig.EncodedJitConfig, nil
+	}
+
+	jitConfig, err := d.github.GenerateJitConfigForRepository(&github.GenerateJitConfigForRepositoryOptions{
+		Username:    registration.Owner,
+		Repository:  registration.Repository,
+		RequestData: request,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return jitConfig.EncodedJitConfig, nil
+}
+
+// registrationToken returns a token config.sh can register a runner with the organization or repository with
+func (d *implementation) registrationToken(registration Registration) (string, error) {
+	if registration.Scope == config.PoolScopeOrganization {
+		registrationToken, err := d.github.CreateRegistrationTokenForOrganization(&github.CreateRegistrationTokenForOrganizationOptions{
+			Organization: registration.Owner,
+		})
+		if err != nil {
+			return "", err
+		}
+
+		return registrationToken.Token, nil
+	}
+
+	registrationToken, err := d.github.GetActionRunnersRegistrationToken(&github.GetActionRunnersRegistrationTokenOptions{
+		Username:   registration.Owner,
+		Repository: registration.Repository,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return registrationToken.Token, nil
+}
+
+// reserve adds the runner to the state, it returns false when its pool is full
+func (d *implementation) reserve(pool *config.Pool, r *Runner) bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if pool.MaxSize > 0 && d.poolSize(pool.Name) >= pool.MaxSize {
+		return false
+	}
+
+	d.runners[r.Name] = r
+	return true
+}
+
+func (d *implementation) forget(r *Runner) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	delete(d.runners, r.Name)
+}
+
+// poolSize must be called with the mutex held
+func (d *implementation) poolSize(poolName string) int {
+	size := 0
+	for _, r := range d.runners {
+		if r.Pool == poolName {
+			size++
+		}
+	}
+
+	return size
+}
+
+// newResources converts the human readable limits of a pool into container Resources
+func newResources(poolResources *config.Resources) (container.Resources, error) {
+	resources := container.Resources{
+		NanoCpus:    int64(poolResources.Cpus * 1e9),
+		CpuShares:   poolResources.CpuShares,
+		PidsLimit:   poolResources.PidsLimit,
+		StorageSize: poolResources.Disk,
+	}
+
+	var err error
+	if poolResources.Memory != "" {
+		resources.Memory, err = units.RAMInBytes(poolResources.Memory)
+		if err != nil {
+			return resources, err
+		}
+	}
+
+	switch poolResources.MemorySwap {
+	case "":
+	case "-1":
+		resources.MemorySwap = -1
+	default:
+		resources.MemorySwap, err = units.RAMInBytes(poolResources.MemorySwap)
+		if err != nil {
+			return resources, err
+		}
+	}
+
+	for _, ulimit := range poolResources.Ulimits {
+		resources.Ulimits = append(resources.Ulimits, container.Ulimit{
+			Name: ulimit.Name,
+			Soft: ulimit.Soft,
+			Hard: ulimit.Hard,
+		})
+	}
+
+	return resources, nil
+}
+
+func newSecurity(poolSecurity *config.Security) container.Security {
+	security := container.Security{
+		CapDrop:         poolSecurity.CapDrop,
+		CapAdd:          poolSecurity.CapAdd,
+		NoNewPrivileges: poolSecurity.NoNewPrivileges,
+		ReadOnlyRootfs:  poolSecurity.ReadOnlyRootfs,
+		SeccompProfile:  poolSecurity.SeccompProfile,
+		AppArmorProfile: poolSecurity.AppArmorProfile,
+		UsernsMode:      poolSecurity.UsernsMode,
+		Runtime:         poolSecurity.Runtime,
+	}
+
+	if len(poolSecurity.Tmpfs) > 0 {
+		security.Tmpfs = map[string]string{}
+		for _, tmpfs := range poolSecurity.Tmpfs {
+			security.Tmpfs[tmpfs.Path] = tmpfs.Options
+		}
+	}
+
+	return security
+}
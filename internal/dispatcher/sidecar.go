@@ -0,0 +1,146 @@
+package dispatcher
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"mirasynth.stream/github-runner/internal/atlas"
+	"mirasynth.stream/github-runner/internal/config"
+	"mirasynth.stream/github-runner/internal/container"
+)
+
+const (
+	sidecarProxy    = "proxy"
+	sidecarDocker   = "docker"
+	sidecarBuildkit = "buildkit"
+
+	dockerPort   = 2375
+	buildkitPort = 1234
+)
+
+// githubHosts are always allowed through the egress proxy, the runner cannot fetch its job or upload logs and
+// artifacts without them
+var githubHosts = []string{
+	"github.com",
+	"*.github.com",
+	"*.githubusercontent.com",
+	"*.githubapp.com",
+	"*.blob.core.windows.net",
+}
+
+// configuredHosts are the hosts of the configured api and web urls, which are not among the githubHosts on GitHub
+// Enterprise Server
+func configuredHosts() []string {
+	var hosts []string
+	for _, configuredUrl := range []string{config.Get().GitHub.ApiUrl, config.Get().GitHub.WebUrl} {
+		parsed, err := url.Parse(configuredUrl)
+		if err == nil && parsed.Hostname() != "" {
+			hosts = append(hosts, parsed.Hostname())
+		}
+	}
+
+	return hosts
+}
+
+// sidecars returns the sidecars the pool asks for, together with the environment that points the runner at them
+func (d *implementation) sidecars(pool *config.Pool, r *Runner) ([]container.Sidecar, []string) {
+	var sidecars []container.Sidecar
+	environment := proxyEnvironment(pool)
+
+	if pool.Network.Egress.Mode == config.EgressModeSidecar {
+		allow := slices.Concat(githubHosts, configuredHosts(), pool.Network.Egress.Allow)
+
+		sidecars = append(sidecars, container.Sidecar{
+			Options: container.Options{
+				Name:        sidecarName(r, sidecarProxy),
+				ImageName:   pool.Network.Egress.Image,
+				Entrypoint:  []string{"/app/githubrunner", "proxy"},
+				Environment: []string{fmt.Sprintf("%s=%s", atlas.EGRESS_ALLOW_ENV, strings.Join(allow, ","))},
+				Labels:      r.labels(d.instanceId),
+				PullPolicy:  container.PullPolicy(pool.PullPolicy),
+				Security: container.Security{
+					CapDrop:         []string{"ALL"},
+					NoNewPrivileges: true,
+				},
+			},
+			Aliases: []string{atlas.EGRESS_PROXY_ALIAS},
+			Bridged: true,
+		})
+	}
+
+	switch pool.Docker.Mode {
+	case config.DockerModeDind:
+		sidecars = append(sidecars, container.Sidecar{
+			Options: container.Options{
+				Name:      sidecarName(r, sidecarDocker),
+				ImageName: pool.Docker.Image,
+				// an empty cert dir makes dockerd listen on plain tcp, the runner network is private to the job
+				Environment: append([]string{"DOCKER_TLS_CERTDIR="}, proxyEnvironment(pool)...),
+				Labels:      r.labels(d.instanceId),
+				PullPolicy:  container.PullPolicy(pool.PullPolicy),
+				Security:    dindSecurity(pool),
+			},
+			Aliases: []string{sidecarDocker},
+		})
+
+		environment = append(environment, fmt.Sprintf("DOCKER_HOST=tcp://%s:%d", sidecarDocker, dockerPort))
+	case config.DockerModeBuildkit:
+		sidecars = append(sidecars, container.Sidecar{
+			Options: container.Options{
+				Name:        sidecarName(r, sidecarBuildkit),
+				ImageName:   pool.Docker.Image,
+				Command:     []string{"--addr", fmt.Sprintf("tcp://0.0.0.0:%d", buildkitPort), "--oci-worker-no-process-sandbox"},
+				Environment: proxyEnvironment(pool),
+				Labels:      r.labels(d.instanceId),
+				PullPolicy:  container.PullPolicy(pool.PullPolicy),
+				Security: container.Security{
+					SeccompProfile:  "unconfined",
+					AppArmorProfile: "unconfined",
+				},
+			},
+			Aliases: []string{sidecarBuildkit},
+		})
+
+		environment = append(environment, fmt.Sprintf("BUILDKIT_HOST=tcp://%s:%d", sidecarBuildkit, buildkitPort))
+	}
+
+	return sidecars, environment
+}
+
+// dindSecurity returns what rootless dockerd needs to set up its own user and mount namespaces and run overlays
+// through fuse-overlayfs, short of privileged mode unless the pool explicitly asks for it
+func dindSecurity(pool *config.Pool) container.Security {
+	if pool.Docker.Privileged {
+		return container.Security{
+			Privileged: true,
+		}
+	}
+
+	return container.Security{
+		SeccompProfile:    "unconfined",
+		AppArmorProfile:   "unconfined",
+		UnmaskSystemPaths: true,
+		Devices:           []string{"/dev/fuse"},
+	}
+}
+
+// poolImages returns the runner image of the pool followed by the images of its sidecars
+func poolImages(pool *config.Pool) []string {
+	images := []string{pool.Image}
+
+	if pool.Network.Egress.Mode == config.EgressModeSidecar {
+		images = append(images, pool.Network.Egress.Image)
+	}
+
+	if pool.Docker.Mode != "" {
+		images = append(images, pool.Docker.Image)
+	}
+
+	return images
+}
+
+func sidecarName(r *Runner, sidecar string) string {
+	return fmt.Sprintf("%s-%s", r.Name, sidecar)
+}
@@ -0,0 +1,92 @@
+package dispatcher
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"mirasynth.stream/github-runner/internal/config"
+)
+
+const retireTimeout = 30 * time.Second
+
+// Provision starts a warm runner for the pool, which registers ahead of any job and waits for GitHub to hand it one.
+// The runner of an organization scoped pool can take the jobs of any repository of the organization.
+func (d *implementation) Provision(ctx context.Context, pool *config.Pool) error {
+	if pool.Scope == config.PoolScopeOrganization {
+		return d.Dispatch(ctx, &Job{
+			Owner:         pool.Organization,
+			RepositoryUrl: NewOrganizationRegistration(pool.Organization).Url(),
+			Pool:          pool.Name,
+		})
+	}
+
+	owner, repository, _ := strings.Cut(pool.Repository, "/")
+
+	return d.Dispatch(ctx, &Job{
+		Owner:         owner,
+		Repository:    repository,
+		RepositoryUrl: NewRepositoryRegistration(owner, repository).Url(),
+		Pool:          pool.Name,
+	})
+}
+
+// Retire scales down an idle runner. The registration goes first, so GitHub cannot hand the runner a job while its
+// container is being stopped, watch cleans up the rest once it has exited.
+func (d *implementation) Retire(ctx context.Context, name string) error {
+	d.mutex.Lock()
+	r, ok := d.runners[name]
+	d.mutex.Unlock()
+
+	if !ok {
+		return fmt.Errorf("runner %q does not exist", name)
+	}
+
+	r.logger().Info("retiring idle runner")
+
+	err := d.deregister(r)
+	if err != nil {
+		return err
+	}
+
+	return d.container.Stop(ctx, r.ContainerId, retireTimeout)
+}
+
+// claimKey returns the store key the job claimed by a warm runner is recorded under, the container label only knows
+// the job a runner was started for
+func claimKey(name string) string {
+	return fmt.Sprintf("runner-job:%s", name)
+}
+
+// claimWarmRunner hands the job to an idle warm runner registered with its organization or repository. GitHub picks
+// which idle runner actually runs the job, the claim keeps the dispatcher from starting another runner and the
+// reconciler counting the warm runner as available. The claim is recorded in the store, so Adopt restores it after a
+// restart instead of taking the runner for an idle one.
+func (d *implementation) claimWarmRunner(job *Job) *Runner {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for _, r := range d.runners {
+		if r.JobId != 0 || r.Pool != job.Pool || r.Owner != job.Owner {
+			continue
+		}
+
+		if r.Scope == config.PoolScopeRepository && r.Repository != job.Repository {
+			continue
+		}
+
+		// a claim that cannot be recorded is no claim, the job gets a runner of its own instead
+		err := d.store.Set(claimKey(r.Name), strconv.FormatInt(job.Id, 10), config.Get().Collector.MaxRunnerAge)
+		if err != nil {
+			r.logger().Error(err)
+			return nil
+		}
+
+		r.JobId = job.Id
+		return r
+	}
+
+	return nil
+}
@@ -26,10 +26,10 @@ type CreateInstallationAccessTokenForAppOptions struct {
 // CreateInstallationAccessTokenForApp returns an access token and the time it expires
 // https://mirasynth.stream/ghapiredir#create-an-installation-access-token-for-an-app
 func (c *ClientImplementation) CreateInstallationAccessTokenForApp(options *CreateInstallationAccessTokenForAppOptions) (*CreateInstallationAccessTokenForAppResponse, error) {
-	url := fmt.Sprintf("https://api.github.com/app/installations/%d/access_tokens", c.installation.Id)
+	path := fmt.Sprintf("/app/installations/%d/access_tokens", c.installation.Id)
 
 	return startRequest(c, &startRequestOptions[CreateInstallationAccessTokenForAppResponse]{
-		URL:         url,
+		Path:        path,
 		Method:      http.MethodPost,
 		UseToken:    false,
 		RequestData: options.RequestData,
@@ -0,0 +1,44 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type CreateRegistrationTokenForOrganizationResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type CreateRegistrationTokenForOrganizationOptions struct {
+	Organization string `json:"organization"`
+}
+
+// CreateRegistrationTokenForOrganization returns a registration token to be used when registering a self-hosted
+// runner on an organization
+// https://mirasynth.stream/ghapiredir#create-a-registration-token-for-an-organization
+func (c *ClientImplementation) CreateRegistrationTokenForOrganization(options *CreateRegistrationTokenForOrganizationOptions) (*CreateRegistrationTokenForOrganizationResponse, error) {
+	path := fmt.Sprintf("/orgs/%s/actions/runners/registration-token", options.Organization)
+
+	return startRequest(c, &startRequestOptions[CreateRegistrationTokenForOrganizationResponse]{
+		Path:     path,
+		Method:   http.MethodPost,
+		UseToken: true,
+		StatusCodes: map[int]statusCode{
+			http.StatusCreated: {},
+			http.StatusUnauthorized: {
+				"the authorization details provided where invalid",
+			},
+			http.StatusForbidden: {
+				"the request was forbidden",
+			},
+			http.StatusNotFound: {
+				"the resource being requested was not found",
+			},
+			defaultStatusCode: {
+				"github organization runner registration token could not be fetched",
+			},
+		},
+	})
+}
@@ -0,0 +1,44 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type CreateRemoveTokenForOrganizationResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type CreateRemoveTokenForOrganizationOptions struct {
+	Organization string `json:"organization"`
+}
+
+// CreateRemoveTokenForOrganization returns a token a self-hosted runner of an organization can remove itself with
+// through config.sh remove
+// https://mirasynth.stream/ghapiredir#create-a-remove-token-for-an-organization
+func (c *ClientImplementation) CreateRemoveTokenForOrganization(options *CreateRemoveTokenForOrganizationOptions) (*CreateRemoveTokenForOrganizationResponse, error) {
+	path := fmt.Sprintf("/orgs/%s/actions/runners/remove-token", options.Organization)
+
+	return startRequest(c, &startRequestOptions[CreateRemoveTokenForOrganizationResponse]{
+		Path:     path,
+		Method:   http.MethodPost,
+		UseToken: true,
+		StatusCodes: map[int]statusCode{
+			http.StatusCreated: {},
+			http.StatusUnauthorized: {
+				"the authorization details provided where invalid",
+			},
+			http.StatusForbidden: {
+				"the request was forbidden",
+			},
+			http.StatusNotFound: {
+				"the resource being requested was not found",
+			},
+			defaultStatusCode: {
+				"github organization runner remove token could not be fetched",
+			},
+		},
+	})
+}
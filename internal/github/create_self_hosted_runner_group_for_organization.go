@@ -0,0 +1,49 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type CreateSelfHostedRunnerGroupForOrganizationResponse struct {
+	RunnerGroup
+}
+
+type CreateSelfHostedRunnerGroupForOrganizationRequest struct {
+	Name string `json:"name"`
+	// Visibility is all, selected or private, selected limits the group to the SelectedRepositoryIds
+	Visibility               string `json:"visibility,omitempty"`
+	SelectedRepositoryIds    []int  `json:"selected_repository_ids,omitempty"`
+	Runners                  []int  `json:"runners,omitempty"`
+	AllowsPublicRepositories bool   `json:"allows_public_repositories"`
+}
+
+type CreateSelfHostedRunnerGroupForOrganizationOptions struct {
+	Organization string `json:"organization"`
+	RequestData  *CreateSelfHostedRunnerGroupForOrganizationRequest
+}
+
+// CreateSelfHostedRunnerGroupForOrganization creates a self-hosted runner group on an organization
+// https://mirasynth.stream/ghapiredir#create-a-self-hosted-runner-group-for-an-organization
+func (c *ClientImplementation) CreateSelfHostedRunnerGroupForOrganization(options *CreateSelfHostedRunnerGroupForOrganizationOptions) (*CreateSelfHostedRunnerGroupForOrganizationResponse, error) {
+	path := fmt.Sprintf("/orgs/%s/actions/runner-groups", options.Organization)
+
+	return startRequest(c, &startRequestOptions[CreateSelfHostedRunnerGroupForOrganizationResponse]{
+		Path:        path,
+		Method:      http.MethodPost,
+		UseToken:    true,
+		RequestData: options.RequestData,
+		StatusCodes: map[int]statusCode{
+			http.StatusCreated: {},
+			http.StatusForbidden: {
+				"the request was forbidden",
+			},
+			http.StatusUnprocessableEntity: {
+				"the entity could not be processed, see additional error for information",
+			},
+			defaultStatusCode: {
+				"github organization runner group could not be created",
+			},
+		},
+	})
+}
@@ -18,7 +18,7 @@ func defaultHeaders(request *http.Request) {
 func (c *ClientImplementation) defaultHeadersJWT(request *http.Request) error {
 	defaultHeaders(request)
 
-	jwt, err := generateJwt(config.GetGitHubClientId(), config.GetGitHubKey())
+	jwt, err := generateJwt(config.Get().GitHub.ClientId, config.Get().GitHub.Key)
 	if err != nil {
 		return err
 	}
@@ -31,11 +31,11 @@ func (c *ClientImplementation) defaultHeadersJWT(request *http.Request) error {
 func (c *ClientImplementation) defaultHeadersToken(request *http.Request) error {
 	defaultHeaders(request)
 
-	err := c.refreshToken()
+	token, err := c.accessToken()
 	if err != nil {
 		return err
 	}
 
-	request.Header.Set("Authorization", fmt.Sprintf("bearer %s", c.auth.Token))
+	request.Header.Set("Authorization", fmt.Sprintf("bearer %s", token))
 	return nil
 }
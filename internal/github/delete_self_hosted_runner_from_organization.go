@@ -0,0 +1,35 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type DeleteSelfHostedRunnerFromOrganizationResponse struct {
+}
+
+type DeleteSelfHostedRunnerFromOrganizationOptions struct {
+	Organization string `json:"organization"`
+	RunnerId     int    `json:"runnerId"`
+}
+
+// DeleteSelfHostedRunnerFromOrganization removes a self-hosted runner registration from an organization
+// https://mirasynth.stream/ghapiredir#delete-a-self-hosted-runner-from-an-organization
+func (c *ClientImplementation) DeleteSelfHostedRunnerFromOrganization(options *DeleteSelfHostedRunnerFromOrganizationOptions) (*DeleteSelfHostedRunnerFromOrganizationResponse, error) {
+	path := fmt.Sprintf("/orgs/%s/actions/runners/%d", options.Organization, options.RunnerId)
+
+	return startRequest(c, &startRequestOptions[DeleteSelfHostedRunnerFromOrganizationResponse]{
+		Path:     path,
+		Method:   http.MethodDelete,
+		UseToken: true,
+		StatusCodes: map[int]statusCode{
+			http.StatusNoContent: {},
+			http.StatusUnprocessableEntity: {
+				"the runner is busy and could not be removed",
+			},
+			defaultStatusCode: {
+				"github action runner could not be removed",
+			},
+		},
+	})
+}
@@ -0,0 +1,36 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type DeleteSelfHostedRunnerFromRepositoryResponse struct {
+}
+
+type DeleteSelfHostedRunnerFromRepositoryOptions struct {
+	Username   string `json:"username"`
+	Repository string `json:"repository"`
+	RunnerId   int    `json:"runnerId"`
+}
+
+// DeleteSelfHostedRunnerFromRepository removes a self-hosted runner registration from a repository
+// https://mirasynth.stream/ghapiredir#delete-a-self-hosted-runner-from-a-repository
+func (c *ClientImplementation) DeleteSelfHostedRunnerFromRepository(options *DeleteSelfHostedRunnerFromRepositoryOptions) (*DeleteSelfHostedRunnerFromRepositoryResponse, error) {
+	path := fmt.Sprintf("/repos/%s/%s/actions/runners/%d", options.Username, options.Repository, options.RunnerId)
+
+	return startRequest(c, &startRequestOptions[DeleteSelfHostedRunnerFromRepositoryResponse]{
+		Path:     path,
+		Method:   http.MethodDelete,
+		UseToken: true,
+		StatusCodes: map[int]statusCode{
+			http.StatusNoContent: {},
+			http.StatusUnprocessableEntity: {
+				"the runner is busy and could not be removed",
+			},
+			defaultStatusCode: {
+				"github action runner could not be removed",
+			},
+		},
+	})
+}
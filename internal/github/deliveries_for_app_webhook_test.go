@@ -0,0 +1,266 @@
+package github
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"mirasynth.stream/github-runner/internal/config"
+)
+
+func newTestClient(t *testing.T, handler http.Handler) *ClientImplementation {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPem := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	configFilePath := path.Join(t.TempDir(), "config.yaml")
+	configContent := fmt.Sprintf("github:\n  appId: 1\n  clientId: test\n  webhook:\n    secrets: [test]\n  key: |\n    %s\n", strings.ReplaceAll(strings.TrimSpace(string(keyPem)), "\n", "\n    "))
+	err = os.WriteFile(configFilePath, []byte(configContent), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = config.SetupConfig(configFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the base url sends every request to the fake api server instead of api.github.com
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	options := &ClientOptions{BaseUrl: server.URL}
+
+	return &ClientImplementation{
+		options:      options,
+		baseUrl:      baseUrl(options),
+		context:      context.Background(),
+		installation: &ClientInstallation{Id: 1},
+		httpClient:   server.Client(),
+		state: &clientState{
+			auth: &ClientToken{Token: "test", TokenExpiresAt: time.Now().Add(time.Hour)},
+		},
+	}
+}
+
+func writeJson(t *testing.T, w http.ResponseWriter, value any) {
+	t.Helper()
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestListDeliveriesForAppWebhook(t *testing.T) {
+	pages := map[string][]HookDelivery{
+		"": {
+			{Id: 6, Guid: "guid-6", Event: "workflow_job", Action: "queued"},
+			{Id: 5, Guid: "guid-5", Event: "workflow_job", Action: "completed"},
+		},
+		"page-2": {
+			{Id: 4, Guid: "guid-4", Event: "ping"},
+			{Id: 3, Guid: "guid-3", Event: "workflow_job", Action: "queued"},
+		},
+		"page-3": {
+			{Id: 2, Guid: "guid-2", Event: "workflow_job", Action: "queued"},
+		},
+	}
+	next := map[string]string{
+		"":       "page-2",
+		"page-2": "page-3",
+	}
+
+	var requests int
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		if r.URL.Path != "/app/hook/deliveries" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "bearer ") {
+			t.Errorf("expected the app jwt to be used, got %q", r.Header.Get("Authorization"))
+		}
+
+		cursor := r.URL.Query().Get("cursor")
+		if nextCursor, ok := next[cursor]; ok {
+			w.Header().Set("Link", fmt.Sprintf(`<http://%s/app/hook/deliveries?per_page=100&cursor=%s>; rel="next"`, r.Host, nextCursor))
+		}
+
+		writeJson(t, w, pages[cursor])
+	}))
+
+	deliveries, err := client.ListDeliveriesForAppWebhook(&ListDeliveriesForAppWebhookOptions{Since: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []int64
+	for _, delivery := range *deliveries {
+		ids = append(ids, delivery.Id)
+	}
+
+	if fmt.Sprint(ids) != "[6 5 4]" {
+		t.Errorf("expected deliveries newer than the cursor, got %v", ids)
+	}
+
+	// the second page reaches the cursor, so the third page must never be requested
+	if requests != 2 {
+		t.Errorf("expected 2 requests, got %d", requests)
+	}
+}
+
+func TestListDeliveriesForAppWebhookDeliveredAfter(t *testing.T) {
+	now := time.Now()
+	pages := map[string][]HookDelivery{
+		"": {
+			{Id: 4, Guid: "guid-4", DeliveredAt: now},
+			{Id: 3, Guid: "guid-3", DeliveredAt: now.Add(-time.Hour)},
+		},
+		"page-2": {
+			{Id: 2, Guid: "guid-2", DeliveredAt: now.Add(-2 * time.Hour)},
+			{Id: 1, Guid: "guid-1", DeliveredAt: now.Add(-3 * time.Hour)},
+		},
+		"page-3": {
+			{Id: 0, Guid: "guid-0", DeliveredAt: now.Add(-4 * time.Hour)},
+		},
+	}
+	next := map[string]string{
+		"":       "page-2",
+		"page-2": "page-3",
+	}
+
+	var requests int
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		cursor := r.URL.Query().Get("cursor")
+		if nextCursor, ok := next[cursor]; ok {
+			w.Header().Set("Link", fmt.Sprintf(`<http://%s/app/hook/deliveries?per_page=100&cursor=%s>; rel="next"`, r.Host, nextCursor))
+		}
+
+		writeJson(t, w, pages[cursor])
+	}))
+
+	deliveries, err := client.ListDeliveriesForAppWebhook(&ListDeliveriesForAppWebhookOptions{
+		DeliveredAfter: now.Add(-150 * time.Minute),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []int64
+	for _, delivery := range *deliveries {
+		ids = append(ids, delivery.Id)
+	}
+
+	if fmt.Sprint(ids) != "[4 3 2]" {
+		t.Errorf("expected deliveries newer than the cutoff, got %v", ids)
+	}
+
+	// the second page goes past the cutoff, so the third page must never be requested
+	if requests != 2 {
+		t.Errorf("expected 2 requests, got %d", requests)
+	}
+}
+
+func TestGetDeliveryForAppWebhook(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/app/hook/deliveries/42" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+
+		w.Write([]byte(`{
+			"id": 42,
+			"guid": "guid-42",
+			"status_code": 502,
+			"event": "workflow_job",
+			"action": "queued",
+			"request": {
+				"headers": {"X-GitHub-Event": "workflow_job"},
+				"payload": {"action": "queued", "workflow_job": {"id": 7}}
+			}
+		}`))
+	}))
+
+	delivery, err := client.GetDeliveryForAppWebhook(&GetDeliveryForAppWebhookOptions{DeliveryId: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if delivery.Guid != "guid-42" || delivery.StatusCode != 502 {
+		t.Errorf("unexpected delivery %+v", delivery.HookDelivery)
+	}
+
+	var payload struct {
+		WorkflowJob WorkflowJob `json:"workflow_job"`
+	}
+	err = json.Unmarshal(delivery.Request.Payload, &payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if payload.WorkflowJob.Id != 7 {
+		t.Errorf("expected the original payload, got %s", delivery.Request.Payload)
+	}
+}
+
+func TestRedeliverDeliveryForAppWebhook(t *testing.T) {
+	var redelivered bool
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/app/hook/deliveries/42/attempts" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+
+		redelivered = true
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{}`))
+	}))
+
+	_, err := client.RedeliverDeliveryForAppWebhook(&RedeliverDeliveryForAppWebhookOptions{DeliveryId: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !redelivered {
+		t.Error("expected a redelivery to be requested")
+	}
+}
+
+func TestBaseUrlWithPath(t *testing.T) {
+	var requestedPath string
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestedPath = r.URL.Path
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{}`))
+	}))
+
+	// GitHub Enterprise Server serves the api under /api/v3
+	client.baseUrl = baseUrl(&ClientOptions{BaseUrl: client.baseUrl + "/api/v3/"})
+
+	_, err := client.RedeliverDeliveryForAppWebhook(&RedeliverDeliveryForAppWebhookOptions{DeliveryId: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if requestedPath != "/api/v3/app/hook/deliveries/42/attempts" {
+		t.Errorf("expected the path to be appended to the base url, got %s", requestedPath)
+	}
+}
@@ -0,0 +1,53 @@
+package events
+
+import (
+	"time"
+
+	"mirasynth.stream/github-runner/internal/github"
+)
+
+type CheckRunOutput struct {
+	Title            string `json:"title"`
+	Summary          string `json:"summary"`
+	Text             string `json:"text"`
+	AnnotationsCount int    `json:"annotations_count"`
+	AnnotationsUrl   string `json:"annotations_url"`
+}
+
+type CheckSuite struct {
+	Id         int64  `json:"id"`
+	NodeId     string `json:"node_id"`
+	HeadBranch string `json:"head_branch"`
+	HeadSha    string `json:"head_sha"`
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion"`
+	Url        string `json:"url"`
+}
+
+type CheckRun struct {
+	Id           int64                         `json:"id"`
+	NodeId       string                        `json:"node_id"`
+	Name         string                        `json:"name"`
+	HeadSha      string                        `json:"head_sha"`
+	ExternalId   string                        `json:"external_id"`
+	Url          string                        `json:"url"`
+	HtmlUrl      string                        `json:"html_url"`
+	DetailsUrl   string                        `json:"details_url"`
+	Status       string                        `json:"status"`
+	Conclusion   string                        `json:"conclusion"`
+	StartedAt    time.Time                     `json:"started_at"`
+	CompletedAt  *time.Time                    `json:"completed_at"`
+	Output       CheckRunOutput                `json:"output"`
+	CheckSuite   CheckSuite                    `json:"check_suite"`
+	PullRequests []github.PullRequestReference `json:"pull_requests"`
+}
+
+// CheckRunEvent is sent when a check run is created, completed, rerequested or has a requested action
+// https://docs.github.com/en/webhooks/webhook-events-and-payloads#check_run
+type CheckRunEvent struct {
+	Event
+	CheckRun        CheckRun `json:"check_run"`
+	RequestedAction *struct {
+		Identifier string `json:"identifier"`
+	} `json:"requested_action"`
+}
@@ -0,0 +1,29 @@
+package events
+
+const (
+	EventPing                     EventType = "ping"
+	EventWorkflowJob              EventType = "workflow_job"
+	EventWorkflowRun              EventType = "workflow_run"
+	EventInstallation             EventType = "installation"
+	EventInstallationRepositories EventType = "installation_repositories"
+	EventCheckRun                 EventType = "check_run"
+
+	ActionAny Action = ""
+
+	ActionQueued     Action = "queued"
+	ActionWaiting    Action = "waiting"
+	ActionInProgress Action = "in_progress"
+	ActionCompleted  Action = "completed"
+	ActionRequested  Action = "requested"
+
+	ActionCreated                Action = "created"
+	ActionDeleted                Action = "deleted"
+	ActionSuspend                Action = "suspend"
+	ActionUnsuspend              Action = "unsuspend"
+	ActionNewPermissionsAccepted Action = "new_permissions_accepted"
+	ActionAdded                  Action = "added"
+	ActionRemoved                Action = "removed"
+
+	ActionRerequested     Action = "rerequested"
+	ActionRequestedAction Action = "requested_action"
+)
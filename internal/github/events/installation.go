@@ -0,0 +1,8 @@
+package events
+
+// InstallationEvent is sent when the GitHub App is installed, uninstalled, suspended or has its permissions changed
+// https://docs.github.com/en/webhooks/webhook-events-and-payloads#installation
+type InstallationEvent struct {
+	Event
+	Repositories []InstallationRepository `json:"repositories"`
+}
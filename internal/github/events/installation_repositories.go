@@ -0,0 +1,10 @@
+package events
+
+// InstallationRepositoriesEvent is sent when repositories are added to or removed from an installation
+// https://docs.github.com/en/webhooks/webhook-events-and-payloads#installation_repositories
+type InstallationRepositoriesEvent struct {
+	Event
+	RepositorySelection string                   `json:"repository_selection"`
+	RepositoriesAdded   []InstallationRepository `json:"repositories_added"`
+	RepositoriesRemoved []InstallationRepository `json:"repositories_removed"`
+}
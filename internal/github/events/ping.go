@@ -0,0 +1,28 @@
+package events
+
+import "time"
+
+type PingHook struct {
+	Type      string    `json:"type"`
+	Id        int       `json:"id"`
+	Name      string    `json:"name"`
+	Active    bool      `json:"active"`
+	Events    []string  `json:"events"`
+	AppId     int       `json:"app_id"`
+	UpdatedAt time.Time `json:"updated_at"`
+	CreatedAt time.Time `json:"created_at"`
+	Config    struct {
+		ContentType string `json:"content_type"`
+		InsecureSsl string `json:"insecure_ssl"`
+		Url         string `json:"url"`
+	} `json:"config"`
+}
+
+// PingEvent is sent when a webhook is first created
+// https://docs.github.com/en/webhooks/webhook-events-and-payloads#ping
+type PingEvent struct {
+	Event
+	Zen    string   `json:"zen"`
+	HookId int      `json:"hook_id"`
+	Hook   PingHook `json:"hook"`
+}
@@ -0,0 +1,58 @@
+package events
+
+import (
+	"time"
+
+	"mirasynth.stream/github-runner/internal/github"
+)
+
+type EventType string
+type Action string
+
+// Event holds the fields that every webhook payload has in common
+type Event struct {
+	Action       Action             `json:"action"`
+	Installation *Installation      `json:"installation"`
+	Organization *Organization      `json:"organization"`
+	Repository   *github.Repository `json:"repository"`
+	Sender       *github.Owner      `json:"sender"`
+}
+
+type Installation struct {
+	Id                  int                            `json:"id"`
+	NodeId              string                         `json:"node_id"`
+	Account             *github.Account                `json:"account"`
+	AppId               int                            `json:"app_id"`
+	AppSlug             string                         `json:"app_slug"`
+	TargetId            int                            `json:"target_id"`
+	TargetType          string                         `json:"target_type"`
+	Permissions         github.InstallationPermissions `json:"permissions"`
+	Events              []string                       `json:"events"`
+	RepositorySelection string                         `json:"repository_selection"`
+	CreatedAt           time.Time                      `json:"created_at"`
+	UpdatedAt           time.Time                      `json:"updated_at"`
+}
+
+type Organization struct {
+	Login            string `json:"login"`
+	Id               int    `json:"id"`
+	NodeId           string `json:"node_id"`
+	Url              string `json:"url"`
+	ReposUrl         string `json:"repos_url"`
+	EventsUrl        string `json:"events_url"`
+	HooksUrl         string `json:"hooks_url"`
+	IssuesUrl        string `json:"issues_url"`
+	MembersUrl       string `json:"members_url"`
+	PublicMembersUrl string `json:"public_members_url"`
+	AvatarUrl        string `json:"avatar_url"`
+	Description      string `json:"description"`
+}
+
+// InstallationRepository is the trimmed down repository object used by the installation events
+type InstallationRepository struct {
+	Id       int    `json:"id"`
+	NodeId   string `json:"node_id"`
+	Name     string `json:"name"`
+	FullName string `json:"full_name"`
+	Private  bool   `json:"private"`
+}
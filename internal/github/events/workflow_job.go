@@ -0,0 +1,10 @@
+package events
+
+import "mirasynth.stream/github-runner/internal/github"
+
+// WorkflowJobEvent is sent when a GitHub Actions workflow job is queued, waiting, in progress or completed
+// https://docs.github.com/en/webhooks/webhook-events-and-payloads#workflow_job
+type WorkflowJobEvent struct {
+	Event
+	WorkflowJob github.WorkflowJob `json:"workflow_job"`
+}
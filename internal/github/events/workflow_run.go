@@ -0,0 +1,28 @@
+package events
+
+import (
+	"time"
+
+	"mirasynth.stream/github-runner/internal/github"
+)
+
+type Workflow struct {
+	Id        int64     `json:"id"`
+	NodeId    string    `json:"node_id"`
+	Name      string    `json:"name"`
+	Path      string    `json:"path"`
+	State     string    `json:"state"`
+	Url       string    `json:"url"`
+	HtmlUrl   string    `json:"html_url"`
+	BadgeUrl  string    `json:"badge_url"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WorkflowRunEvent is sent when a GitHub Actions workflow run is requested, in progress or completed
+// https://docs.github.com/en/webhooks/webhook-events-and-payloads#workflow_run
+type WorkflowRunEvent struct {
+	Event
+	Workflow    Workflow           `json:"workflow"`
+	WorkflowRun github.WorkflowRun `json:"workflow_run"`
+}
@@ -0,0 +1,45 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type GenerateJitConfigForOrganizationResponse struct {
+	Runner           Runner `json:"runner"`
+	EncodedJitConfig string `json:"encoded_jit_config"`
+}
+
+type GenerateJitConfigForOrganizationOptions struct {
+	Organization string `json:"organization"`
+	RequestData  *GenerateJitConfigRequest
+}
+
+// GenerateJitConfigForOrganization registers a just-in-time runner on an organization and returns the encoded config
+// the runner is started with, no registration token is needed
+// https://mirasynth.stream/ghapiredir#create-configuration-for-a-just-in-time-runner-for-an-organization
+func (c *ClientImplementation) GenerateJitConfigForOrganization(options *GenerateJitConfigForOrganizationOptions) (*GenerateJitConfigForOrganizationResponse, error) {
+	path := fmt.Sprintf("/orgs/%s/actions/runners/generate-jitconfig", options.Organization)
+
+	return startRequest(c, &startRequestOptions[GenerateJitConfigForOrganizationResponse]{
+		Path:        path,
+		Method:      http.MethodPost,
+		UseToken:    true,
+		RequestData: options.RequestData,
+		StatusCodes: map[int]statusCode{
+			http.StatusCreated: {},
+			http.StatusNotFound: {
+				"the resource being requested was not found",
+			},
+			http.StatusConflict: {
+				"a runner with the same name already exists",
+			},
+			http.StatusUnprocessableEntity: {
+				"the entity could not be processed, see additional error for information",
+			},
+			defaultStatusCode: {
+				"github action runner jit config could not be generated",
+			},
+		},
+	})
+}
@@ -0,0 +1,53 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type GenerateJitConfigForRepositoryResponse struct {
+	Runner           Runner `json:"runner"`
+	EncodedJitConfig string `json:"encoded_jit_config"`
+}
+
+type GenerateJitConfigRequest struct {
+	Name          string   `json:"name"`
+	RunnerGroupId int      `json:"runner_group_id"`
+	Labels        []string `json:"labels"`
+	WorkFolder    string   `json:"work_folder,omitempty"`
+}
+
+type GenerateJitConfigForRepositoryOptions struct {
+	Username    string `json:"username"`
+	Repository  string `json:"repository"`
+	RequestData *GenerateJitConfigRequest
+}
+
+// GenerateJitConfigForRepository registers a just-in-time runner on a repository and returns the encoded config the
+// runner is started with, no registration token is needed
+// https://mirasynth.stream/ghapiredir#create-configuration-for-a-just-in-time-runner-for-a-repository
+func (c *ClientImplementation) GenerateJitConfigForRepository(options *GenerateJitConfigForRepositoryOptions) (*GenerateJitConfigForRepositoryResponse, error) {
+	path := fmt.Sprintf("/repos/%s/%s/actions/runners/generate-jitconfig", options.Username, options.Repository)
+
+	return startRequest(c, &startRequestOptions[GenerateJitConfigForRepositoryResponse]{
+		Path:        path,
+		Method:      http.MethodPost,
+		UseToken:    true,
+		RequestData: options.RequestData,
+		StatusCodes: map[int]statusCode{
+			http.StatusCreated: {},
+			http.StatusNotFound: {
+				"the resource being requested was not found",
+			},
+			http.StatusConflict: {
+				"a runner with the same name already exists",
+			},
+			http.StatusUnprocessableEntity: {
+				"the entity could not be processed, see additional error for information",
+			},
+			defaultStatusCode: {
+				"github action runner jit config could not be generated",
+			},
+		},
+	})
+}
@@ -12,24 +12,23 @@ type GetActionRunnersRegistrationTokenResponse struct {
 }
 
 type GetActionRunnersRegistrationTokenOptions struct {
-	Username     string `json:"username"`
-	Organization string `json:"organization"`
-	Repository   string `json:"repository"`
-	Token        string `json:"token"`
+	Username   string `json:"username"`
+	Repository string `json:"repository"`
+	Token      string `json:"token"`
 }
 
 // GetActionRunnersRegistrationToken returns a registration token to be used when registering a self-hosted runner
-// on GitHub.
-// https://mirasynth.stream/ghapiredir#get-a-self-hosted-runner-for-a-repository
+// on a repository, see CreateRegistrationTokenForOrganization for organization runners.
+// https://mirasynth.stream/ghapiredir#create-a-registration-token-for-a-repository
 func (c *ClientImplementation) GetActionRunnersRegistrationToken(options *GetActionRunnersRegistrationTokenOptions) (*GetActionRunnersRegistrationTokenResponse, error) {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/actions/runners/registration-token", options.Username, options.Repository)
+	path := fmt.Sprintf("/repos/%s/%s/actions/runners/registration-token", options.Username, options.Repository)
 
 	return startRequest(c, &startRequestOptions[GetActionRunnersRegistrationTokenResponse]{
-		URL:      url,
+		Path:     path,
 		Method:   http.MethodPost,
 		UseToken: true,
 		StatusCodes: map[int]statusCode{
-			http.StatusOK: {},
+			http.StatusCreated: {},
 			http.StatusUnauthorized: {
 				"the authorization details provided where invalid",
 			},
@@ -27,10 +27,10 @@ type GetAuthenticatedAppOptions struct {
 // GetAuthenticatedApp return the app that belongs to the client data
 // https://mirasynth.stream/ghapiredir#get-the-authenticated-app
 func (c *ClientImplementation) GetAuthenticatedApp(_ *GetAuthenticatedAppOptions) (*GetAuthenticatedAppResponse, error) {
-	url := fmt.Sprintf("https://api.github.com/app")
+	path := fmt.Sprintf("/app")
 
 	return startRequest(c, &startRequestOptions[GetAuthenticatedAppResponse]{
-		URL:      url,
+		Path:     path,
 		Method:   http.MethodGet,
 		UseToken: false,
 		StatusCodes: map[int]statusCode{
@@ -0,0 +1,48 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+type GetDeliveryForAppWebhookResponse struct {
+	HookDelivery
+	Url     string `json:"url"`
+	Request struct {
+		Headers map[string]string `json:"headers"`
+		Payload json.RawMessage   `json:"payload"`
+	} `json:"request"`
+	Response struct {
+		Headers map[string]string `json:"headers"`
+		Payload string            `json:"payload"`
+	} `json:"response"`
+}
+
+type GetDeliveryForAppWebhookOptions struct {
+	DeliveryId int64 `json:"deliveryId"`
+}
+
+// GetDeliveryForAppWebhook returns a single delivery of the app webhook, including the payload that was sent
+// https://mirasynth.stream/ghapiredir#get-a-delivery-for-an-app-webhook
+func (c *ClientImplementation) GetDeliveryForAppWebhook(options *GetDeliveryForAppWebhookOptions) (*GetDeliveryForAppWebhookResponse, error) {
+	path := fmt.Sprintf("/app/hook/deliveries/%d", options.DeliveryId)
+
+	return startRequest(c, &startRequestOptions[GetDeliveryForAppWebhookResponse]{
+		Path:     path,
+		Method:   http.MethodGet,
+		UseToken: false,
+		StatusCodes: map[int]statusCode{
+			http.StatusOK: {},
+			http.StatusBadRequest: {
+				"the delivery request was invalid",
+			},
+			http.StatusUnprocessableEntity: {
+				"the entity could not be processed, see additional error for information",
+			},
+			defaultStatusCode: {
+				"app webhook delivery could not be fetched",
+			},
+		},
+	})
+}
@@ -35,10 +35,10 @@ type GetInstallationForAuthenticatedAppOptions struct {
 // GetInstallationForAuthenticatedApp return the installed app that belongs to the client data
 // https://mirasynth.stream/ghapiredir#get-the-authenticated-app
 func (c *ClientImplementation) GetInstallationForAuthenticatedApp(options *GetInstallationForAuthenticatedAppOptions) (*GetInstallationForAuthenticatedAppResponse, error) {
-	url := fmt.Sprintf("https://api.github.com/app/installations/%d", options.InstallationId)
+	path := fmt.Sprintf("/app/installations/%d", options.InstallationId)
 
 	return startRequest(c, &startRequestOptions[GetInstallationForAuthenticatedAppResponse]{
-		URL:      url,
+		Path:     path,
 		Method:   http.MethodGet,
 		UseToken: false,
 		StatusCodes: map[int]statusCode{
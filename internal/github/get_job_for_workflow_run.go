@@ -0,0 +1,32 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type GetJobForWorkflowRunResponse WorkflowJob
+
+type GetJobForWorkflowRunOptions struct {
+	Username   string `json:"username"`
+	Repository string `json:"repository"`
+	JobId      int64  `json:"jobId"`
+}
+
+// GetJobForWorkflowRun returns a single job of a workflow run
+// https://mirasynth.stream/ghapiredir#get-a-job-for-a-workflow-run
+func (c *ClientImplementation) GetJobForWorkflowRun(options *GetJobForWorkflowRunOptions) (*GetJobForWorkflowRunResponse, error) {
+	path := fmt.Sprintf("/repos/%s/%s/actions/jobs/%d", options.Username, options.Repository, options.JobId)
+
+	return startRequest(c, &startRequestOptions[GetJobForWorkflowRunResponse]{
+		Path:     path,
+		Method:   http.MethodGet,
+		UseToken: true,
+		StatusCodes: map[int]statusCode{
+			http.StatusOK: {},
+			defaultStatusCode: {
+				"workflow job could not be fetched",
+			},
+		},
+	})
+}
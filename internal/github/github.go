@@ -8,32 +8,57 @@ import (
 	"io"
 	"mirasynth.stream/github-runner/internal/config"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 )
 
 const (
 	defaultStatusCode = 0
+	// requestTimeout bounds a single request, retries and rate limit waits come on top of it
+	requestTimeout = 30 * time.Second
 )
 
 var instance *ClientImplementation
 
-var backoffSchedule = []time.Duration{
-	1 * time.Second,
-	3 * time.Second,
-	10 * time.Second,
-	37 * time.Second,
-	101 * time.Second,
-}
-
 type Client interface {
 	GetAuthenticatedApp(*GetAuthenticatedAppOptions) (*GetAuthenticatedAppResponse, error)
 	CreateInstallationAccessTokenForApp(*CreateInstallationAccessTokenForAppOptions) (*CreateInstallationAccessTokenForAppResponse, error)
 	GetActionRunnersRegistrationToken(*GetActionRunnersRegistrationTokenOptions) (*GetActionRunnersRegistrationTokenResponse, error)
+	ListSelfHostedRunnersForRepository(*LListSelfHostedRunnersForRepositoryOptions) (*ListSelfHostedRunnersForRepositoryResponse, error)
+	GenerateJitConfigForRepository(*GenerateJitConfigForRepositoryOptions) (*GenerateJitConfigForRepositoryResponse, error)
+	GenerateJitConfigForOrganization(*GenerateJitConfigForOrganizationOptions) (*GenerateJitConfigForOrganizationResponse, error)
+	DeleteSelfHostedRunnerFromRepository(*DeleteSelfHostedRunnerFromRepositoryOptions) (*DeleteSelfHostedRunnerFromRepositoryResponse, error)
+	ListSelfHostedRunnersForOrganization(*ListSelfHostedRunnersForOrganizationOptions) (*ListSelfHostedRunnersForOrganizationResponse, error)
+	CreateRegistrationTokenForOrganization(*CreateRegistrationTokenForOrganizationOptions) (*CreateRegistrationTokenForOrganizationResponse, error)
+	CreateRemoveTokenForOrganization(*CreateRemoveTokenForOrganizationOptions) (*CreateRemoveTokenForOrganizationResponse, error)
+	DeleteSelfHostedRunnerFromOrganization(*DeleteSelfHostedRunnerFromOrganizationOptions) (*DeleteSelfHostedRunnerFromOrganizationResponse, error)
+
+	ListSelfHostedRunnerGroupsForOrganization(*ListSelfHostedRunnerGroupsForOrganizationOptions) (*ListSelfHostedRunnerGroupsForOrganizationResponse, error)
+	CreateSelfHostedRunnerGroupForOrganization(*CreateSelfHostedRunnerGroupForOrganizationOptions) (*CreateSelfHostedRunnerGroupForOrganizationResponse, error)
+	UpdateSelfHostedRunnerGroupForOrganization(*UpdateSelfHostedRunnerGroupForOrganizationOptions) (*UpdateSelfHostedRunnerGroupForOrganizationResponse, error)
+	ListRepositoryAccessToSelfHostedRunnerGroupInOrganization(*ListRepositoryAccessToSelfHostedRunnerGroupInOrganizationOptions) (*ListRepositoryAccessToSelfHostedRunnerGroupInOrganizationResponse, error)
+	SetRepositoryAccessForSelfHostedRunnerGroupInOrganization(*SetRepositoryAccessForSelfHostedRunnerGroupInOrganizationOptions) (*SetRepositoryAccessForSelfHostedRunnerGroupInOrganizationResponse, error)
 
 	GetInstallationForAuthenticatedApp(*GetInstallationForAuthenticatedAppOptions) (*GetInstallationForAuthenticatedAppResponse, error)
 	ListInstallationsForAuthenticatedApp(*ListInstallationsForAuthenticatedAppOptions) (*ListInstallationsForAuthenticatedAppResponse, error)
 
 	ListUserRepositories(*ListUserRepositoriesOptions) (*ListUserRepositoriesResponse, error)
+	ListRepositoriesForAppInstallation(*ListRepositoriesForAppInstallationOptions) (*ListRepositoriesForAppInstallationResponse, error)
+
+	ListDeliveriesForAppWebhook(*ListDeliveriesForAppWebhookOptions) (*ListDeliveriesForAppWebhookResponse, error)
+	GetDeliveryForAppWebhook(*GetDeliveryForAppWebhookOptions) (*GetDeliveryForAppWebhookResponse, error)
+	RedeliverDeliveryForAppWebhook(*RedeliverDeliveryForAppWebhookOptions) (*RedeliverDeliveryForAppWebhookResponse, error)
+
+	ListWorkflowRunsForRepository(*ListWorkflowRunsForRepositoryOptions) (*ListWorkflowRunsForRepositoryResponse, error)
+	ListJobsForWorkflowRun(*ListJobsForWorkflowRunOptions) (*ListJobsForWorkflowRunResponse, error)
+	GetJobForWorkflowRun(*GetJobForWorkflowRunOptions) (*GetJobForWorkflowRunResponse, error)
+
+	// WithContext returns a client whose requests, and the rate limit waits in between, end with the context. It
+	// shares the token, the cache and the quota with the client it was derived from.
+	WithContext(context.Context) Client
+	// RateLimit returns the quota of the installation as of the last response
+	RateLimit() RateLimit
 
 	refreshToken() error
 	defaultHeadersJWT(request *http.Request) error
@@ -42,6 +67,9 @@ type Client interface {
 
 type ClientOptions struct {
 	Repositories []ClientRepository `json:"repositories"`
+	// BaseUrl is the url the api is served from, such as https://ghe.corp/api/v3 for GitHub Enterprise Server. It
+	// defaults to github.apiUrl from the config.
+	BaseUrl string `json:"baseUrl"`
 }
 
 type ClientInstallation struct {
@@ -51,9 +79,25 @@ type ClientInstallation struct {
 
 type ClientImplementation struct {
 	options      *ClientOptions
-	auth         *ClientToken
+	baseUrl      string
 	installation *ClientInstallation
 	context      context.Context
+	httpClient   *http.Client
+	state        *clientState
+}
+
+// clientState is shared by a client and the clients WithContext derives from it
+type clientState struct {
+	conditionalMutex sync.Mutex
+	conditionalCache map[string]conditionalResponse
+
+	rateLimitMutex sync.Mutex
+	rateLimit      RateLimit
+
+	// authMutex is held for the whole refresh, so concurrent requests wait for one new token instead of each
+	// fetching their own
+	authMutex sync.Mutex
+	auth      *ClientToken
 }
 
 func GetClient(ctx context.Context, options *ClientOptions) (Client, error) {
@@ -67,8 +111,11 @@ func GetClient(ctx context.Context, options *ClientOptions) (Client, error) {
 
 	instance = &ClientImplementation{
 		options:      options,
+		baseUrl:      baseUrl(options),
 		context:      ctx,
 		installation: &ClientInstallation{},
+		httpClient:   &http.Client{Timeout: requestTimeout},
+		state:        &clientState{},
 	}
 
 	err := validateClientOptions(options)
@@ -82,7 +129,7 @@ func GetClient(ctx context.Context, options *ClientOptions) (Client, error) {
 	}
 
 	for _, authenticatedApp := range *authenticatedApps {
-		if authenticatedApp.AppId == config.GetGitHubAppId() {
+		if authenticatedApp.AppId == config.Get().GitHub.AppId {
 			instance.installation.Id = authenticatedApp.Id
 			instance.installation.Permissions = authenticatedApp.Permissions
 			break
@@ -101,6 +148,21 @@ func GetClient(ctx context.Context, options *ClientOptions) (Client, error) {
 	return instance, nil
 }
 
+func (c *ClientImplementation) WithContext(ctx context.Context) Client {
+	derived := *c
+	derived.context = ctx
+	return &derived
+}
+
+// baseUrl returns the api url of the options or the config, without a trailing slash so paths can be appended to it
+func baseUrl(options *ClientOptions) string {
+	if options.BaseUrl != "" {
+		return strings.TrimSuffix(options.BaseUrl, "/")
+	}
+
+	return strings.TrimSuffix(config.Get().GitHub.ApiUrl, "/")
+}
+
 func validateClientOptions(options *ClientOptions) error {
 	if options == nil {
 		return fmt.Errorf("options argument must be provided to the client")
@@ -110,7 +172,29 @@ func validateClientOptions(options *ClientOptions) error {
 }
 
 func (c *ClientImplementation) refreshToken() error {
-	if c.auth != nil && c.auth.TokenExpiresAt.After(time.Now()) {
+	c.state.authMutex.Lock()
+	defer c.state.authMutex.Unlock()
+
+	return c.refreshTokenLocked()
+}
+
+// accessToken returns the installation token, refreshing it first once it expired
+func (c *ClientImplementation) accessToken() (string, error) {
+	c.state.authMutex.Lock()
+	defer c.state.authMutex.Unlock()
+
+	err := c.refreshTokenLocked()
+	if err != nil {
+		return "", err
+	}
+
+	return c.state.auth.Token, nil
+}
+
+// refreshTokenLocked fetches a new installation token unless the current one is still valid, the caller holds the
+// auth mutex. The token itself is requested with the jwt, which never needs the mutex.
+func (c *ClientImplementation) refreshTokenLocked() error {
+	if c.state.auth != nil && c.state.auth.TokenExpiresAt.After(time.Now()) {
 		return nil
 	}
 
@@ -125,13 +209,11 @@ func (c *ClientImplementation) refreshToken() error {
 		return err
 	}
 
-	if c.auth == nil {
-		c.auth = &ClientToken{}
+	c.state.auth = &ClientToken{
+		Token:          response.Token,
+		TokenExpiresAt: response.ExpiresAt,
 	}
 
-	c.auth.Token = response.Token
-	c.auth.TokenExpiresAt = response.ExpiresAt
-
 	return nil
 }
 
@@ -143,15 +225,29 @@ type pagination[T any] struct {
 	PerPage     int
 	StartPage   int
 	PageReducer func(accumulator T, result T) *T
+	// LastPage can end the pagination early, after the result has been reduced
+	LastPage func(result T) bool
+	// UseLinkHeader follows the rel="next" Link header instead of counting pages, for cursor based endpoints
+	UseLinkHeader bool
+	nextUrl       string
 }
 
 type startRequestOptions[T any] struct {
-	URL         string
+	// Path is appended to the base url of the client, so the same endpoints work against GitHub Enterprise Server
+	Path        string
 	Method      string
 	UseToken    bool
 	RequestData any
 	StatusCodes map[int]statusCode
 	Pagination  *pagination[T]
+	// Conditional sends the ETag of the previous response, a 304 is answered from cache and does not count against
+	// the rate limit
+	Conditional bool
+}
+
+type conditionalResponse struct {
+	ETag string
+	Body []byte
 }
 
 func startRequest[T any](c *ClientImplementation, options *startRequestOptions[T]) (*T, error) {
@@ -166,35 +262,9 @@ func startRequest[T any](c *ClientImplementation, options *startRequestOptions[T
 
 	var returnResult T
 
-	var response *http.Response
 	for {
-		for _, backoff := range backoffSchedule {
-			response, err = singleRequest(c, options, &requestDataBytes)
-
-			if response == nil {
-				return nil, err
-			}
-
-			statusCodeBehaviour, ok := options.StatusCodes[response.StatusCode]
-			if !ok {
-				statusCodeBehaviour, ok = options.StatusCodes[defaultStatusCode]
-			}
-			if !ok {
-				statusCodeBehaviour = statusCode{
-					ErrorMessage: "an error has occurred",
-				}
-			}
-
-			strLen := len(statusCodeBehaviour.ErrorMessage)
-			if strLen == 0 {
-				break
-			}
-
-			err = handleError(response, &statusCodeBehaviour)
-			time.Sleep(backoff)
-		}
-
-		if response == nil || err != nil {
+		response, err := retryRequest(c, options, &requestDataBytes)
+		if err != nil {
 			return nil, err
 		}
 
@@ -230,7 +300,21 @@ func startRequest[T any](c *ClientImplementation, options *startRequestOptions[T
 			returnResult = *pageReducerResult
 			response.Body.Close()
 			options.Pagination.StartPage++
-			continue
+
+			if options.Pagination.LastPage != nil && options.Pagination.LastPage(result) {
+				return &returnResult, nil
+			}
+
+			if !options.Pagination.UseLinkHeader {
+				continue
+			}
+
+			options.Pagination.nextUrl = nextLink(response.Header.Get("Link"))
+			if options.Pagination.nextUrl != "" {
+				continue
+			}
+
+			return &returnResult, nil
 		}
 
 		response.Body.Close()
@@ -238,16 +322,97 @@ func startRequest[T any](c *ClientImplementation, options *startRequestOptions[T
 	}
 }
 
+// retryRequest sends the request until it gets a response the options expect. Rate limits are waited out, server
+// errors and dropped connections are retried with a growing backoff and any other error is returned right away.
+func retryRequest[T any](c *ClientImplementation, options *startRequestOptions[T], requestDataBytes *[]byte) (*http.Response, error) {
+	backoff := initialBackoff
+	for attempt := 1; ; attempt++ {
+		if options.UseToken {
+			err := c.waitForQuota()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		response, err := singleRequest(c, options, requestDataBytes)
+		if err != nil {
+			if c.context.Err() != nil || attempt >= maxAttempts || !retryable(nil, err) {
+				return nil, err
+			}
+
+			err = c.sleep(backoff)
+			if err != nil {
+				return nil, err
+			}
+
+			backoff *= 2
+			continue
+		}
+
+		if options.UseToken {
+			c.updateRateLimit(response.Header)
+		}
+
+		statusCodeBehaviour, ok := options.StatusCodes[response.StatusCode]
+		if !ok {
+			statusCodeBehaviour, ok = options.StatusCodes[defaultStatusCode]
+		}
+		if !ok {
+			statusCodeBehaviour = statusCode{
+				ErrorMessage: "an error has occurred",
+			}
+		}
+
+		if statusCodeBehaviour.ErrorMessage == "" {
+			return response, nil
+		}
+
+		// the body is read up front to tell a rate limit from missing permissions, handleError reads it again
+		body, err := io.ReadAll(response.Body)
+		response.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		response.Body = io.NopCloser(bytes.NewReader(body))
+
+		wait, secondary, limited := rateLimitWait(response, body)
+		err = handleError(response, &statusCodeBehaviour)
+		if attempt >= maxAttempts {
+			return nil, err
+		}
+
+		if limited {
+			err = c.waitForRateLimit(wait, secondary)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if !retryable(response, nil) {
+			return nil, err
+		}
+
+		err = c.sleep(backoff)
+		if err != nil {
+			return nil, err
+		}
+
+		backoff *= 2
+	}
+}
+
 func singleRequest[T any](c *ClientImplementation, options *startRequestOptions[T], requestDataBytes *[]byte) (*http.Response, error) {
-	request, err := http.NewRequest(options.Method, options.URL, bytes.NewBuffer(*requestDataBytes))
+	requestUrl := c.baseUrl + options.Path
+	if options.Pagination != nil && options.Pagination.nextUrl != "" {
+		requestUrl = options.Pagination.nextUrl
+	}
+
+	request, err := http.NewRequestWithContext(c.context, options.Method, requestUrl, bytes.NewBuffer(*requestDataBytes))
 	if err != nil {
 		return nil, err
 	}
 
-	deadlineContext, cancel := context.WithTimeout(c.context, time.Second*5)
-	defer cancel()
-	request.WithContext(deadlineContext)
-
 	if options.UseToken {
 		err = c.defaultHeadersToken(request)
 	} else {
@@ -258,15 +423,84 @@ func singleRequest[T any](c *ClientImplementation, options *startRequestOptions[
 		return nil, err
 	}
 
-	if options.Pagination != nil {
+	if options.Pagination != nil && options.Pagination.nextUrl == "" {
 		query := request.URL.Query()
-		query.Add("page", fmt.Sprintf("%d", options.Pagination.StartPage))
+		if !options.Pagination.UseLinkHeader {
+			query.Add("page", fmt.Sprintf("%d", options.Pagination.StartPage))
+		}
 		query.Add("per_page", fmt.Sprintf("%d", options.Pagination.PerPage))
 		request.URL.RawQuery = query.Encode()
 	}
 
-	client := &http.Client{}
-	return client.Do(request)
+	if !options.Conditional {
+		return c.httpClient.Do(request)
+	}
+
+	return conditionalRequest(c, request)
+}
+
+// conditionalRequest sends the request with the ETag of the last response for the same url. A 304 is turned back
+// into the cached 200, so callers never have to know the response came from the cache.
+func conditionalRequest(c *ClientImplementation, request *http.Request) (*http.Response, error) {
+	cacheKey := request.URL.String()
+
+	c.state.conditionalMutex.Lock()
+	cached, ok := c.state.conditionalCache[cacheKey]
+	c.state.conditionalMutex.Unlock()
+
+	if ok {
+		request.Header.Set("If-None-Match", cached.ETag)
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return response, err
+	}
+
+	if response.StatusCode == http.StatusNotModified && ok {
+		response.Body.Close()
+		response.StatusCode = http.StatusOK
+		response.Body = io.NopCloser(bytes.NewReader(cached.Body))
+		return response, nil
+	}
+
+	etag := response.Header.Get("ETag")
+	if response.StatusCode != http.StatusOK || etag == "" {
+		return response, nil
+	}
+
+	body, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	c.state.conditionalMutex.Lock()
+	if c.state.conditionalCache == nil {
+		c.state.conditionalCache = map[string]conditionalResponse{}
+	}
+	c.state.conditionalCache[cacheKey] = conditionalResponse{
+		ETag: etag,
+		Body: body,
+	}
+	c.state.conditionalMutex.Unlock()
+
+	response.Body = io.NopCloser(bytes.NewReader(body))
+	return response, nil
+}
+
+// nextLink returns the rel="next" url from a Link header, or an empty string on the last page
+func nextLink(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		linkUrl, params, ok := strings.Cut(link, ";")
+		if !ok || !strings.Contains(params, `rel="next"`) {
+			continue
+		}
+
+		return strings.Trim(strings.TrimSpace(linkUrl), "<>")
+	}
+
+	return ""
 }
 
 func handleError(response *http.Response, options *statusCode) error {
@@ -0,0 +1,65 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type ListDeliveriesForAppWebhookResponse []HookDelivery
+
+type ListDeliveriesForAppWebhookOptions struct {
+	// Since stops the listing at the first delivery with an id lower or equal to it, 0 lists every delivery
+	Since int64 `json:"since"`
+	// DeliveredAfter stops the listing at the first delivery delivered at or before it, the zero time lists every
+	// delivery
+	DeliveredAfter time.Time `json:"deliveredAfter"`
+}
+
+// include reports whether the delivery is newer than both the cursor and the delivery time of the options
+func (o *ListDeliveriesForAppWebhookOptions) include(delivery HookDelivery) bool {
+	return delivery.Id > o.Since && (o.DeliveredAfter.IsZero() || delivery.DeliveredAt.After(o.DeliveredAfter))
+}
+
+// ListDeliveriesForAppWebhook returns the deliveries of the app webhook, newest first
+// https://mirasynth.stream/ghapiredir#list-deliveries-for-an-app-webhook
+func (c *ClientImplementation) ListDeliveriesForAppWebhook(options *ListDeliveriesForAppWebhookOptions) (*ListDeliveriesForAppWebhookResponse, error) {
+	path := fmt.Sprintf("/app/hook/deliveries")
+
+	return startRequest(c, &startRequestOptions[ListDeliveriesForAppWebhookResponse]{
+		Path:     path,
+		Method:   http.MethodGet,
+		UseToken: false,
+		StatusCodes: map[int]statusCode{
+			http.StatusOK: {},
+			http.StatusBadRequest: {
+				"the delivery cursor was invalid",
+			},
+			http.StatusUnprocessableEntity: {
+				"the entity could not be processed, see additional error for information",
+			},
+			defaultStatusCode: {
+				"app webhook deliveries could not be fetched",
+			},
+		},
+		Pagination: &pagination[ListDeliveriesForAppWebhookResponse]{
+			PerPage:       100,
+			StartPage:     1,
+			UseLinkHeader: true,
+			PageReducer: func(accumulator ListDeliveriesForAppWebhookResponse, result ListDeliveriesForAppWebhookResponse) *ListDeliveriesForAppWebhookResponse {
+				if len(result) <= 0 {
+					return nil
+				}
+				for _, delivery := range result {
+					if options.include(delivery) {
+						accumulator = append(accumulator, delivery)
+					}
+				}
+				return &accumulator
+			},
+			LastPage: func(result ListDeliveriesForAppWebhookResponse) bool {
+				return len(result) > 0 && !options.include(result[len(result)-1])
+			},
+		},
+	})
+}
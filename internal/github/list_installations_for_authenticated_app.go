@@ -34,10 +34,10 @@ type ListInstallationsForAuthenticatedAppOptions struct {
 // ListInstallationsForAuthenticatedApp return the installed apps that belongs to the client data
 // https://mirasynth.stream/ghapiredir#get-the-authenticated-app
 func (c *ClientImplementation) ListInstallationsForAuthenticatedApp(_ *ListInstallationsForAuthenticatedAppOptions) (*ListInstallationsForAuthenticatedAppResponse, error) {
-	url := fmt.Sprintf("https://api.github.com/app/installations")
+	path := fmt.Sprintf("/app/installations")
 
 	return startRequest(c, &startRequestOptions[ListInstallationsForAuthenticatedAppResponse]{
-		URL:      url,
+		Path:     path,
 		Method:   http.MethodGet,
 		UseToken: false,
 		Pagination: &pagination[ListInstallationsForAuthenticatedAppResponse]{
@@ -0,0 +1,51 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type ListJobsForWorkflowRunResponse struct {
+	TotalCount int           `json:"total_count"`
+	Jobs       []WorkflowJob `json:"jobs"`
+}
+
+type ListJobsForWorkflowRunOptions struct {
+	Username   string `json:"username"`
+	Repository string `json:"repository"`
+	RunId      int64  `json:"runId"`
+}
+
+// ListJobsForWorkflowRun returns the jobs of the latest attempt of a workflow run
+// https://mirasynth.stream/ghapiredir#list-jobs-for-a-workflow-run
+func (c *ClientImplementation) ListJobsForWorkflowRun(options *ListJobsForWorkflowRunOptions) (*ListJobsForWorkflowRunResponse, error) {
+	path := fmt.Sprintf("/repos/%s/%s/actions/runs/%d/jobs?filter=latest", options.Username, options.Repository, options.RunId)
+
+	return startRequest(c, &startRequestOptions[ListJobsForWorkflowRunResponse]{
+		Path:        path,
+		Method:      http.MethodGet,
+		UseToken:    true,
+		Conditional: true,
+		StatusCodes: map[int]statusCode{
+			http.StatusOK: {},
+			defaultStatusCode: {
+				"workflow run jobs could not be fetched",
+			},
+		},
+		Pagination: &pagination[ListJobsForWorkflowRunResponse]{
+			PerPage:   100,
+			StartPage: 1,
+			PageReducer: func(accumulator ListJobsForWorkflowRunResponse, result ListJobsForWorkflowRunResponse) *ListJobsForWorkflowRunResponse {
+				if len(result.Jobs) <= 0 {
+					return nil
+				}
+				accumulator.TotalCount = result.TotalCount
+				accumulator.Jobs = append(accumulator.Jobs, result.Jobs...)
+				return &accumulator
+			},
+			LastPage: func(result ListJobsForWorkflowRunResponse) bool {
+				return len(result.Jobs) < 100
+			},
+		},
+	})
+}
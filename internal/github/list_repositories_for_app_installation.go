@@ -0,0 +1,48 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type ListRepositoriesForAppInstallationResponse struct {
+	TotalCount   int          `json:"total_count"`
+	Repositories []Repository `json:"repositories"`
+}
+
+type ListRepositoriesForAppInstallationOptions struct {
+}
+
+// ListRepositoriesForAppInstallation returns every repository the installation has been granted access to
+// https://mirasynth.stream/ghapiredir#list-repositories-accessible-to-the-app-installation
+func (c *ClientImplementation) ListRepositoriesForAppInstallation(_ *ListRepositoriesForAppInstallationOptions) (*ListRepositoriesForAppInstallationResponse, error) {
+	path := fmt.Sprintf("/installation/repositories")
+
+	return startRequest(c, &startRequestOptions[ListRepositoriesForAppInstallationResponse]{
+		Path:        path,
+		Method:      http.MethodGet,
+		UseToken:    true,
+		Conditional: true,
+		StatusCodes: map[int]statusCode{
+			http.StatusOK: {},
+			defaultStatusCode: {
+				"installation repositories could not be fetched",
+			},
+		},
+		Pagination: &pagination[ListRepositoriesForAppInstallationResponse]{
+			PerPage:   100,
+			StartPage: 1,
+			PageReducer: func(accumulator ListRepositoriesForAppInstallationResponse, result ListRepositoriesForAppInstallationResponse) *ListRepositoriesForAppInstallationResponse {
+				if len(result.Repositories) <= 0 {
+					return nil
+				}
+				accumulator.TotalCount = result.TotalCount
+				accumulator.Repositories = append(accumulator.Repositories, result.Repositories...)
+				return &accumulator
+			},
+			LastPage: func(result ListRepositoriesForAppInstallationResponse) bool {
+				return len(result.Repositories) < 100
+			},
+		},
+	})
+}
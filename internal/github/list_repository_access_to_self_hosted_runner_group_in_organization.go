@@ -0,0 +1,49 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type ListRepositoryAccessToSelfHostedRunnerGroupInOrganizationResponse struct {
+	TotalCount   int          `json:"total_count"`
+	Repositories []Repository `json:"repositories"`
+}
+
+type ListRepositoryAccessToSelfHostedRunnerGroupInOrganizationOptions struct {
+	Organization  string `json:"organization"`
+	RunnerGroupId int    `json:"runnerGroupId"`
+}
+
+// ListRepositoryAccessToSelfHostedRunnerGroupInOrganization returns the repositories that can use the runners of a
+// runner group with selected visibility
+// https://mirasynth.stream/ghapiredir#list-repository-access-to-a-self-hosted-runner-group-in-an-organization
+func (c *ClientImplementation) ListRepositoryAccessToSelfHostedRunnerGroupInOrganization(options *ListRepositoryAccessToSelfHostedRunnerGroupInOrganizationOptions) (*ListRepositoryAccessToSelfHostedRunnerGroupInOrganizationResponse, error) {
+	path := fmt.Sprintf("/orgs/%s/actions/runner-groups/%d/repositories", options.Organization, options.RunnerGroupId)
+
+	return startRequest(c, &startRequestOptions[ListRepositoryAccessToSelfHostedRunnerGroupInOrganizationResponse]{
+		Path:     path,
+		Method:   http.MethodGet,
+		UseToken: true,
+		StatusCodes: map[int]statusCode{
+			http.StatusOK: {},
+			http.StatusNotFound: {
+				"the resource being requested was not found",
+			},
+			defaultStatusCode: {
+				"github organization runner group repositories could not be listed",
+			},
+		},
+		Pagination: &pagination[ListRepositoryAccessToSelfHostedRunnerGroupInOrganizationResponse]{
+			PerPage:   100,
+			StartPage: 1,
+			PageReducer: func(accumulator ListRepositoryAccessToSelfHostedRunnerGroupInOrganizationResponse, result ListRepositoryAccessToSelfHostedRunnerGroupInOrganizationResponse) *ListRepositoryAccessToSelfHostedRunnerGroupInOrganizationResponse {
+				if len(result.Repositories) <= 0 {
+					return nil
+				}
+				accumulator.Repositories = append(accumulator.Repositories, result.Repositories...)
+				return &accumulator
+			},
+		},
+	})
+}
@@ -0,0 +1,45 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type ListSelfHostedRunnerGroupsForOrganizationResponse struct {
+	TotalCount   int           `json:"total_count"`
+	RunnerGroups []RunnerGroup `json:"runner_groups"`
+}
+
+type ListSelfHostedRunnerGroupsForOrganizationOptions struct {
+	Organization string `json:"organization"`
+}
+
+// ListSelfHostedRunnerGroupsForOrganization returns a list of all the self-hosted runner groups of an organization
+// https://mirasynth.stream/ghapiredir#list-self-hosted-runner-groups-for-an-organization
+func (c *ClientImplementation) ListSelfHostedRunnerGroupsForOrganization(options *ListSelfHostedRunnerGroupsForOrganizationOptions) (*ListSelfHostedRunnerGroupsForOrganizationResponse, error) {
+	path := fmt.Sprintf("/orgs/%s/actions/runner-groups", options.Organization)
+
+	return startRequest(c, &startRequestOptions[ListSelfHostedRunnerGroupsForOrganizationResponse]{
+		Path:        path,
+		Method:      http.MethodGet,
+		UseToken:    true,
+		Conditional: true,
+		StatusCodes: map[int]statusCode{
+			http.StatusOK: {},
+			defaultStatusCode: {
+				"github organization runner groups could not be listed",
+			},
+		},
+		Pagination: &pagination[ListSelfHostedRunnerGroupsForOrganizationResponse]{
+			PerPage:   100,
+			StartPage: 1,
+			PageReducer: func(accumulator ListSelfHostedRunnerGroupsForOrganizationResponse, result ListSelfHostedRunnerGroupsForOrganizationResponse) *ListSelfHostedRunnerGroupsForOrganizationResponse {
+				if len(result.RunnerGroups) <= 0 {
+					return nil
+				}
+				accumulator.RunnerGroups = append(accumulator.RunnerGroups, result.RunnerGroups...)
+				return &accumulator
+			},
+		},
+	})
+}
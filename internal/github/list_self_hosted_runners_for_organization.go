@@ -0,0 +1,44 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type ListSelfHostedRunnersForOrganizationResponse struct {
+	TotalCount int      `json:"total_count"`
+	Runners    []Runner `json:"runners"`
+}
+
+type ListSelfHostedRunnersForOrganizationOptions struct {
+	Organization string `json:"organization"`
+}
+
+// ListSelfHostedRunnersForOrganization returns a list of all the GitHub self-hosted runners for an organization
+// https://mirasynth.stream/ghapiredir#list-self-hosted-runners-for-an-organization
+func (c *ClientImplementation) ListSelfHostedRunnersForOrganization(options *ListSelfHostedRunnersForOrganizationOptions) (*ListSelfHostedRunnersForOrganizationResponse, error) {
+	path := fmt.Sprintf("/orgs/%s/actions/runners", options.Organization)
+
+	return startRequest(c, &startRequestOptions[ListSelfHostedRunnersForOrganizationResponse]{
+		Path:     path,
+		Method:   http.MethodGet,
+		UseToken: true,
+		StatusCodes: map[int]statusCode{
+			http.StatusOK: {},
+			defaultStatusCode: {
+				"github organization self-hosted runners could not be listed",
+			},
+		},
+		Pagination: &pagination[ListSelfHostedRunnersForOrganizationResponse]{
+			PerPage:   100,
+			StartPage: 1,
+			PageReducer: func(accumulator ListSelfHostedRunnersForOrganizationResponse, result ListSelfHostedRunnersForOrganizationResponse) *ListSelfHostedRunnersForOrganizationResponse {
+				if len(result.Runners) <= 0 {
+					return nil
+				}
+				accumulator.Runners = append(accumulator.Runners, result.Runners...)
+				return &accumulator
+			},
+		},
+	})
+}
@@ -18,10 +18,10 @@ type LListSelfHostedRunnersForRepositoryOptions struct {
 // ListSelfHostedRunnersForRepository returns a list of all the GitHub self-hosted runners for a repository
 // https://mirasynth.stream/ghapiredir#list-self-hosted-runners-for-a-repository
 func (c *ClientImplementation) ListSelfHostedRunnersForRepository(options *LListSelfHostedRunnersForRepositoryOptions) (*ListSelfHostedRunnersForRepositoryResponse, error) {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/actions/runners", options.Username, options.Repository)
+	path := fmt.Sprintf("/repos/%s/%s/actions/runners", options.Username, options.Repository)
 
 	return startRequest(c, &startRequestOptions[ListSelfHostedRunnersForRepositoryResponse]{
-		URL:      url,
+		Path:     path,
 		Method:   http.MethodGet,
 		UseToken: true,
 		StatusCodes: map[int]statusCode{
@@ -14,10 +14,10 @@ type ListUserRepositoriesOptions struct {
 // ListUserRepositories returns a list of all the repositories that belong to the specified user on GitHub.
 // https://mirasynth.stream/ghapiredir#list-repositories-for-a-user
 func (c *ClientImplementation) ListUserRepositories(options *ListUserRepositoriesOptions) (*ListUserRepositoriesResponse, error) {
-	url := fmt.Sprintf("https://api.github.com/users/%s/repos", options.Username)
+	path := fmt.Sprintf("/users/%s/repos", options.Username)
 
 	return startRequest(c, &startRequestOptions[ListUserRepositoriesResponse]{
-		URL:      url,
+		Path:     path,
 		Method:   http.MethodGet,
 		UseToken: true,
 		StatusCodes: map[int]statusCode{
@@ -0,0 +1,58 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+type ListWorkflowRunsForRepositoryResponse struct {
+	TotalCount   int           `json:"total_count"`
+	WorkflowRuns []WorkflowRun `json:"workflow_runs"`
+}
+
+type ListWorkflowRunsForRepositoryOptions struct {
+	Username   string `json:"username"`
+	Repository string `json:"repository"`
+	// Status only lists runs with the status, such as queued or in_progress, when set
+	Status string `json:"status"`
+}
+
+// ListWorkflowRunsForRepository returns the workflow runs of a repository
+// https://mirasynth.stream/ghapiredir#list-workflow-runs-for-a-repository
+func (c *ClientImplementation) ListWorkflowRunsForRepository(options *ListWorkflowRunsForRepositoryOptions) (*ListWorkflowRunsForRepositoryResponse, error) {
+	query := url.Values{}
+	if options.Status != "" {
+		query.Set("status", options.Status)
+	}
+
+	path := fmt.Sprintf("/repos/%s/%s/actions/runs?%s", options.Username, options.Repository, query.Encode())
+
+	return startRequest(c, &startRequestOptions[ListWorkflowRunsForRepositoryResponse]{
+		Path:        path,
+		Method:      http.MethodGet,
+		UseToken:    true,
+		Conditional: true,
+		StatusCodes: map[int]statusCode{
+			http.StatusOK: {},
+			defaultStatusCode: {
+				"workflow runs could not be fetched",
+			},
+		},
+		Pagination: &pagination[ListWorkflowRunsForRepositoryResponse]{
+			PerPage:   100,
+			StartPage: 1,
+			PageReducer: func(accumulator ListWorkflowRunsForRepositoryResponse, result ListWorkflowRunsForRepositoryResponse) *ListWorkflowRunsForRepositoryResponse {
+				if len(result.WorkflowRuns) <= 0 {
+					return nil
+				}
+				accumulator.TotalCount = result.TotalCount
+				accumulator.WorkflowRuns = append(accumulator.WorkflowRuns, result.WorkflowRuns...)
+				return &accumulator
+			},
+			LastPage: func(result ListWorkflowRunsForRepositoryResponse) bool {
+				return len(result.WorkflowRuns) < 100
+			},
+		},
+	})
+}
@@ -0,0 +1,176 @@
+package github
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"mirasynth.stream/github-runner/internal/metrics"
+)
+
+// maxAttempts is how many times a request is sent before a server error, a dropped connection or a rate limit is
+// given up on
+const maxAttempts = 5
+
+// initialBackoff is the wait before the first retry of a server error or a dropped connection, it doubles with every
+// retry after that
+const initialBackoff = time.Second
+
+// secondaryRateLimitWait is how long GitHub asks to back off from a secondary rate limit that says nothing else
+const secondaryRateLimitWait = time.Minute
+
+// RateLimit is the quota of the installation as GitHub reported it on the last response
+type RateLimit struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Used      int       `json:"used"`
+	Reset     time.Time `json:"reset"`
+	Resource  string    `json:"resource"`
+}
+
+// RateLimitError is returned instead of waiting out a rate limit when the wait would go past the deadline of the
+// context of the client
+type RateLimitError struct {
+	// Secondary is set for the secondary, abuse detection, limits that GitHub does not announce up front
+	Secondary bool
+	// RetryAt is when GitHub accepts requests again
+	RetryAt time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	kind := "primary"
+	if e.Secondary {
+		kind = "secondary"
+	}
+
+	return fmt.Sprintf("github %s rate limit exceeded, requests are accepted again at %s", kind, e.RetryAt.Format(time.RFC3339))
+}
+
+// RateLimit returns the quota of the installation, it is zero until the first request made with the installation
+// token
+func (c *ClientImplementation) RateLimit() RateLimit {
+	c.state.rateLimitMutex.Lock()
+	defer c.state.rateLimitMutex.Unlock()
+
+	return c.state.rateLimit
+}
+
+// updateRateLimit records the quota headers of a response made with the installation token. App requests signed
+// with the jwt have a quota of their own, which would only muddle the one of the installation.
+func (c *ClientImplementation) updateRateLimit(header http.Header) {
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+
+	resource := header.Get("X-RateLimit-Resource")
+	if resource != "" && resource != "core" {
+		return
+	}
+
+	limit, _ := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	used, _ := strconv.Atoi(header.Get("X-RateLimit-Used"))
+	reset, _ := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+
+	c.state.rateLimitMutex.Lock()
+	c.state.rateLimit = RateLimit{
+		Limit:     limit,
+		Remaining: remaining,
+		Used:      used,
+		Reset:     time.Unix(reset, 0),
+		Resource:  resource,
+	}
+	c.state.rateLimitMutex.Unlock()
+
+	metrics.GitHubRateLimitRemaining.Set(int64(remaining))
+}
+
+// waitForQuota holds a request back until the reset when the last response used up the quota, rather than sending
+// it only to be turned away
+func (c *ClientImplementation) waitForQuota() error {
+	rateLimit := c.RateLimit()
+	if rateLimit.Limit == 0 || rateLimit.Remaining > 0 || !rateLimit.Reset.After(time.Now()) {
+		return nil
+	}
+
+	return c.waitForRateLimit(time.Until(rateLimit.Reset), false)
+}
+
+// rateLimitWait reports whether a response was turned away by a rate limit and how long to wait before sending the
+// request again. A 403 also means missing permissions, so only one that says it is about a rate limit counts.
+func rateLimitWait(response *http.Response, body []byte) (time.Duration, bool, bool) {
+	if response.StatusCode != http.StatusForbidden && response.StatusCode != http.StatusTooManyRequests {
+		return 0, false, false
+	}
+
+	secondary := strings.Contains(strings.ToLower(string(body)), "secondary rate limit")
+
+	retryAfter, err := strconv.Atoi(response.Header.Get("Retry-After"))
+	if err == nil {
+		return time.Duration(retryAfter) * time.Second, true, true
+	}
+
+	if response.Header.Get("X-RateLimit-Remaining") == "0" {
+		reset, err := strconv.ParseInt(response.Header.Get("X-RateLimit-Reset"), 10, 64)
+		if err == nil {
+			return max(time.Until(time.Unix(reset, 0)), 0), false, true
+		}
+	}
+
+	if secondary || response.StatusCode == http.StatusTooManyRequests {
+		return secondaryRateLimitWait, true, true
+	}
+
+	return 0, false, false
+}
+
+// waitForRateLimit sleeps through a rate limit, unless the context would expire first
+func (c *ClientImplementation) waitForRateLimit(wait time.Duration, secondary bool) error {
+	retryAt := time.Now().Add(wait)
+
+	deadline, ok := c.context.Deadline()
+	if ok && retryAt.After(deadline) {
+		return &RateLimitError{
+			Secondary: secondary,
+			RetryAt:   retryAt,
+		}
+	}
+
+	metrics.GitHubRateLimitWaits.Add(1)
+	log.WithFields(log.Fields{
+		"component": "github",
+		"secondary": secondary,
+		"retryAt":   retryAt.Format(time.RFC3339),
+	}).Warn("waiting for the github rate limit to reset")
+
+	return c.sleep(wait)
+}
+
+// sleep waits for the duration or until the context of the client is done
+func (c *ClientImplementation) sleep(wait time.Duration) error {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-c.context.Done():
+		return c.context.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// retryable reports whether a failed request is worth sending again, a server error or a connection that dropped
+// may well go through the next time while anything else is going to fail the same way
+func retryable(response *http.Response, err error) bool {
+	if err != nil {
+		var urlErr *url.Error
+		return errors.As(err, &urlErr)
+	}
+
+	return response.StatusCode >= http.StatusInternalServerError
+}
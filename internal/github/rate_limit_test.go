@@ -0,0 +1,94 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSecondaryRateLimitIsRetried(t *testing.T) {
+	var requests int
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "4999")
+		w.Header().Set("X-RateLimit-Reset", "1700000000")
+
+		if requests == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message": "You have exceeded a secondary rate limit"}`))
+			return
+		}
+
+		w.Write([]byte(`{"total_count": 0, "runners": []}`))
+	}))
+
+	_, err := client.ListSelfHostedRunnersForOrganization(&ListSelfHostedRunnersForOrganizationOptions{Organization: "mirasynth"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if requests != 2 {
+		t.Errorf("expected the request to be sent again after the rate limit, got %d requests", requests)
+	}
+
+	rateLimit := client.RateLimit()
+	if rateLimit.Limit != 5000 || rateLimit.Remaining != 4999 || !rateLimit.Reset.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("expected the quota of the last response, got %+v", rateLimit)
+	}
+}
+
+func TestRateLimitBeyondDeadlineFailsFast(t *testing.T) {
+	var requests int
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", "4102444800")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message": "API rate limit exceeded"}`))
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	_, err := client.WithContext(ctx).ListSelfHostedRunnersForOrganization(&ListSelfHostedRunnersForOrganizationOptions{Organization: "mirasynth"})
+
+	var rateLimitErr *RateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		t.Fatalf("expected a rate limit error, got %v", err)
+	}
+
+	if rateLimitErr.Secondary {
+		t.Error("expected a primary rate limit")
+	}
+
+	// the quota is known to be used up, so the next request must not even be sent
+	_, err = client.WithContext(ctx).ListSelfHostedRunnersForOrganization(&ListSelfHostedRunnersForOrganizationOptions{Organization: "mirasynth"})
+	if !errors.As(err, &rateLimitErr) || requests != 1 {
+		t.Errorf("expected the request to be held back, got %v after %d requests", err, requests)
+	}
+}
+
+func TestMissingPermissionsAreNotRetried(t *testing.T) {
+	var requests int
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message": "Resource not accessible by integration"}`))
+	}))
+
+	_, err := client.ListSelfHostedRunnersForOrganization(&ListSelfHostedRunnersForOrganizationOptions{Organization: "mirasynth"})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if requests != 1 {
+		t.Errorf("expected a single request, got %d", requests)
+	}
+}
@@ -0,0 +1,37 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type RedeliverDeliveryForAppWebhookResponse struct {
+}
+
+type RedeliverDeliveryForAppWebhookOptions struct {
+	DeliveryId int64 `json:"deliveryId"`
+}
+
+// RedeliverDeliveryForAppWebhook asks GitHub to send a delivery of the app webhook again
+// https://mirasynth.stream/ghapiredir#redeliver-a-delivery-for-an-app-webhook
+func (c *ClientImplementation) RedeliverDeliveryForAppWebhook(options *RedeliverDeliveryForAppWebhookOptions) (*RedeliverDeliveryForAppWebhookResponse, error) {
+	path := fmt.Sprintf("/app/hook/deliveries/%d/attempts", options.DeliveryId)
+
+	return startRequest(c, &startRequestOptions[RedeliverDeliveryForAppWebhookResponse]{
+		Path:     path,
+		Method:   http.MethodPost,
+		UseToken: false,
+		StatusCodes: map[int]statusCode{
+			http.StatusAccepted: {},
+			http.StatusBadRequest: {
+				"the redelivery request was invalid",
+			},
+			http.StatusUnprocessableEntity: {
+				"the entity could not be processed, see additional error for information",
+			},
+			defaultStatusCode: {
+				"app webhook delivery could not be redelivered",
+			},
+		},
+	})
+}
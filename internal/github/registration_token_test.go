@@ -0,0 +1,41 @@
+package github
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRegistrationTokens(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("unexpected method %s", r.Method)
+		}
+
+		// github creates a new token on every request and answers with 201
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"token": "registration-token", "expires_at": "2030-01-01T00:00:00Z"}`))
+	}))
+
+	repositoryToken, err := client.GetActionRunnersRegistrationToken(&GetActionRunnersRegistrationTokenOptions{
+		Username:   "owner",
+		Repository: "repository",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if repositoryToken.Token != "registration-token" {
+		t.Errorf("expected the repository token, got %q", repositoryToken.Token)
+	}
+
+	organizationToken, err := client.CreateRegistrationTokenForOrganization(&CreateRegistrationTokenForOrganizationOptions{
+		Organization: "owner",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if organizationToken.Token != "registration-token" {
+		t.Errorf("expected the organization token, got %q", organizationToken.Token)
+	}
+}
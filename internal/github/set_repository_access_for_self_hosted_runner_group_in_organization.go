@@ -0,0 +1,42 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type SetRepositoryAccessForSelfHostedRunnerGroupInOrganizationResponse struct {
+}
+
+type SetRepositoryAccessForSelfHostedRunnerGroupInOrganizationRequest struct {
+	SelectedRepositoryIds []int `json:"selected_repository_ids"`
+}
+
+type SetRepositoryAccessForSelfHostedRunnerGroupInOrganizationOptions struct {
+	Organization  string `json:"organization"`
+	RunnerGroupId int    `json:"runnerGroupId"`
+	RequestData   *SetRepositoryAccessForSelfHostedRunnerGroupInOrganizationRequest
+}
+
+// SetRepositoryAccessForSelfHostedRunnerGroupInOrganization replaces the repositories that can use the runners of a
+// runner group with selected visibility
+// https://mirasynth.stream/ghapiredir#set-repository-access-for-a-self-hosted-runner-group-in-an-organization
+func (c *ClientImplementation) SetRepositoryAccessForSelfHostedRunnerGroupInOrganization(options *SetRepositoryAccessForSelfHostedRunnerGroupInOrganizationOptions) (*SetRepositoryAccessForSelfHostedRunnerGroupInOrganizationResponse, error) {
+	path := fmt.Sprintf("/orgs/%s/actions/runner-groups/%d/repositories", options.Organization, options.RunnerGroupId)
+
+	return startRequest(c, &startRequestOptions[SetRepositoryAccessForSelfHostedRunnerGroupInOrganizationResponse]{
+		Path:        path,
+		Method:      http.MethodPut,
+		UseToken:    true,
+		RequestData: options.RequestData,
+		StatusCodes: map[int]statusCode{
+			http.StatusNoContent: {},
+			http.StatusNotFound: {
+				"the resource being requested was not found",
+			},
+			defaultStatusCode: {
+				"github organization runner group repositories could not be set",
+			},
+		},
+	})
+}
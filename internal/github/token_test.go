@@ -0,0 +1,53 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConcurrentTokenRefresh(t *testing.T) {
+	var tokenRequests atomic.Int32
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/access_tokens") {
+			tokenRequests.Add(1)
+			w.WriteHeader(http.StatusCreated)
+			writeJson(t, w, map[string]any{"token": "fresh", "expires_at": time.Now().Add(time.Hour)})
+			return
+		}
+
+		if r.Header.Get("Authorization") != "bearer fresh" {
+			t.Errorf("expected the refreshed token, got %q", r.Header.Get("Authorization"))
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		writeJson(t, w, map[string]any{"token": "registration-token"})
+	}))
+	client.state.auth.TokenExpiresAt = time.Now().Add(-time.Minute)
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := client.WithContext(context.Background()).GetActionRunnersRegistrationToken(&GetActionRunnersRegistrationTokenOptions{
+				Username:   "owner",
+				Repository: "repository",
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// the requests wait for the one refresh rather than each fetching a token of their own
+	if tokenRequests.Load() != 1 {
+		t.Errorf("expected 1 token request, got %d", tokenRequests.Load())
+	}
+}
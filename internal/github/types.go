@@ -188,15 +188,122 @@ type Label struct {
 }
 
 type Runner struct {
-	Id     int     `json:"id"`
-	Name   string  `json:"name"`
-	Os     string  `json:"os"`
-	Status string  `json:"status"`
-	Busy   bool    `json:"busy"`
-	Labels []Label `json:"labels"`
+	Id            int     `json:"id"`
+	Name          string  `json:"name"`
+	Os            string  `json:"os"`
+	Status        string  `json:"status"`
+	Busy          bool    `json:"busy"`
+	Labels        []Label `json:"labels"`
+	RunnerGroupId int     `json:"runner_group_id"`
+}
+
+type RunnerGroup struct {
+	Id                       int      `json:"id"`
+	Name                     string   `json:"name"`
+	Visibility               string   `json:"visibility"`
+	Default                  bool     `json:"default"`
+	Inherited                bool     `json:"inherited"`
+	AllowsPublicRepositories bool     `json:"allows_public_repositories"`
+	RestrictedToWorkflows    bool     `json:"restricted_to_workflows"`
+	SelectedWorkflows        []string `json:"selected_workflows"`
+	SelectedRepositoriesUrl  string   `json:"selected_repositories_url"`
+	RunnersUrl               string   `json:"runners_url"`
+}
+
+type WorkflowStep struct {
+	Name        string     `json:"name"`
+	Status      string     `json:"status"`
+	Conclusion  string     `json:"conclusion"`
+	Number      int        `json:"number"`
+	StartedAt   *time.Time `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+}
+
+type WorkflowJob struct {
+	Id              int64          `json:"id"`
+	RunId           int64          `json:"run_id"`
+	RunUrl          string         `json:"run_url"`
+	RunAttempt      int            `json:"run_attempt"`
+	NodeId          string         `json:"node_id"`
+	HeadSha         string         `json:"head_sha"`
+	HeadBranch      string         `json:"head_branch"`
+	Url             string         `json:"url"`
+	HtmlUrl         string         `json:"html_url"`
+	Status          string         `json:"status"`
+	Conclusion      string         `json:"conclusion"`
+	CreatedAt       time.Time      `json:"created_at"`
+	StartedAt       time.Time      `json:"started_at"`
+	CompletedAt     *time.Time     `json:"completed_at"`
+	Name            string         `json:"name"`
+	WorkflowName    string         `json:"workflow_name"`
+	Steps           []WorkflowStep `json:"steps"`
+	CheckRunUrl     string         `json:"check_run_url"`
+	Labels          []string       `json:"labels"`
+	RunnerId        int64          `json:"runner_id"`
+	RunnerName      string         `json:"runner_name"`
+	RunnerGroupId   int64          `json:"runner_group_id"`
+	RunnerGroupName string         `json:"runner_group_name"`
+}
+
+type PullRequestReference struct {
+	Id     int    `json:"id"`
+	Number int    `json:"number"`
+	Url    string `json:"url"`
+	Head   struct {
+		Ref string `json:"ref"`
+		Sha string `json:"sha"`
+	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+		Sha string `json:"sha"`
+	} `json:"base"`
+}
+
+type WorkflowRun struct {
+	Id              int64                  `json:"id"`
+	Name            string                 `json:"name"`
+	NodeId          string                 `json:"node_id"`
+	HeadBranch      string                 `json:"head_branch"`
+	HeadSha         string                 `json:"head_sha"`
+	Path            string                 `json:"path"`
+	DisplayTitle    string                 `json:"display_title"`
+	RunNumber       int                    `json:"run_number"`
+	RunAttempt      int                    `json:"run_attempt"`
+	Event           string                 `json:"event"`
+	Status          string                 `json:"status"`
+	Conclusion      string                 `json:"conclusion"`
+	WorkflowId      int64                  `json:"workflow_id"`
+	CheckSuiteId    int64                  `json:"check_suite_id"`
+	Url             string                 `json:"url"`
+	HtmlUrl         string                 `json:"html_url"`
+	JobsUrl         string                 `json:"jobs_url"`
+	LogsUrl         string                 `json:"logs_url"`
+	PullRequests    []PullRequestReference `json:"pull_requests"`
+	CreatedAt       time.Time              `json:"created_at"`
+	UpdatedAt       time.Time              `json:"updated_at"`
+	RunStartedAt    time.Time              `json:"run_started_at"`
+	Actor           *Owner                 `json:"actor"`
+	TriggeringActor *Owner                 `json:"triggering_actor"`
+	Repository      *Repository            `json:"repository"`
+	HeadRepository  *Repository            `json:"head_repository"`
 }
 
 type Error struct {
 	Message          string `json:"message"`
 	DocumentationUrl string `json:"documentation_url"`
 }
+
+type HookDelivery struct {
+	Id             int64     `json:"id"`
+	Guid           string    `json:"guid"`
+	DeliveredAt    time.Time `json:"delivered_at"`
+	Redelivery     bool      `json:"redelivery"`
+	Duration       float64   `json:"duration"`
+	Status         string    `json:"status"`
+	StatusCode     int       `json:"status_code"`
+	Event          string    `json:"event"`
+	Action         string    `json:"action"`
+	InstallationId int64     `json:"installation_id"`
+	RepositoryId   int64     `json:"repository_id"`
+	ThrottledAt    time.Time `json:"throttled_at"`
+}
@@ -0,0 +1,45 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type UpdateSelfHostedRunnerGroupForOrganizationResponse struct {
+	RunnerGroup
+}
+
+type UpdateSelfHostedRunnerGroupForOrganizationRequest struct {
+	Name string `json:"name"`
+	// Visibility is all, selected or private, the repositories of a selected group are set separately
+	Visibility               string `json:"visibility,omitempty"`
+	AllowsPublicRepositories bool   `json:"allows_public_repositories"`
+}
+
+type UpdateSelfHostedRunnerGroupForOrganizationOptions struct {
+	Organization  string `json:"organization"`
+	RunnerGroupId int    `json:"runnerGroupId"`
+	RequestData   *UpdateSelfHostedRunnerGroupForOrganizationRequest
+}
+
+// UpdateSelfHostedRunnerGroupForOrganization updates the name and visibility of a self-hosted runner group
+// https://mirasynth.stream/ghapiredir#update-a-self-hosted-runner-group-for-an-organization
+func (c *ClientImplementation) UpdateSelfHostedRunnerGroupForOrganization(options *UpdateSelfHostedRunnerGroupForOrganizationOptions) (*UpdateSelfHostedRunnerGroupForOrganizationResponse, error) {
+	path := fmt.Sprintf("/orgs/%s/actions/runner-groups/%d", options.Organization, options.RunnerGroupId)
+
+	return startRequest(c, &startRequestOptions[UpdateSelfHostedRunnerGroupForOrganizationResponse]{
+		Path:        path,
+		Method:      http.MethodPatch,
+		UseToken:    true,
+		RequestData: options.RequestData,
+		StatusCodes: map[int]statusCode{
+			http.StatusOK: {},
+			http.StatusNotFound: {
+				"the resource being requested was not found",
+			},
+			defaultStatusCode: {
+				"github organization runner group could not be updated",
+			},
+		},
+	})
+}
@@ -0,0 +1,19 @@
+package metrics
+
+import "expvar"
+
+// metrics are published through expvar, the server exposes them as json on /api/v1/metrics
+
+var CollectorRuns = expvar.NewInt("collector_runs")
+var CollectorErrors = expvar.NewInt("collector_errors")
+var CollectorLastRun = expvar.NewInt("collector_last_run_unix")
+var CollectorContainersRemoved = expvar.NewInt("collector_containers_removed")
+var CollectorRunnersDeregistered = expvar.NewInt("collector_runners_deregistered")
+var CollectorVolumesRemoved = expvar.NewInt("collector_volumes_removed")
+
+var RecoveryFailures = expvar.NewInt("recovery_failures")
+
+var RunnersOutdated = expvar.NewInt("runners_outdated")
+
+var GitHubRateLimitRemaining = expvar.NewInt("github_rate_limit_remaining")
+var GitHubRateLimitWaits = expvar.NewInt("github_rate_limit_waits")
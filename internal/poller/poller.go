@@ -0,0 +1,157 @@
+package poller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"mirasynth.stream/github-runner/internal/config"
+	"mirasynth.stream/github-runner/internal/dispatcher"
+	"mirasynth.stream/github-runner/internal/github"
+)
+
+// workflowRunStatuses are the run statuses that can still have queued jobs, a run stays in_progress while its later
+// jobs wait for a runner
+var workflowRunStatuses = []string{"queued", "in_progress"}
+
+// Poller finds queued jobs by listing workflow runs, for hosts that cannot receive webhooks
+type Poller interface {
+	// Poll submits every queued job of every repository the installation can access and returns the number of api
+	// requests it took
+	Poll(context.Context) (int, error)
+	// Run polls until the context is cancelled, spacing the polls out so they stay within the hourly request budget
+	Run(context.Context)
+}
+
+type implementation struct {
+	github     github.Client
+	dispatcher dispatcher.Dispatcher
+}
+
+func New(githubClient github.Client, d dispatcher.Dispatcher) Poller {
+	return &implementation{
+		github:     githubClient,
+		dispatcher: d,
+	}
+}
+
+func (p *implementation) Run(ctx context.Context) {
+	for {
+		requests, err := p.Poll(ctx)
+		if err != nil {
+			log.WithField("component", "poller").Error(err)
+		}
+
+		interval := pollInterval(requests)
+		log.WithFields(log.Fields{
+			"component": "poller",
+			"requests":  requests,
+			"interval":  interval.String(),
+		}).Debug("poll finished")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// pollInterval stretches the configured interval when a poll needs more requests than the hourly budget allows for
+func pollInterval(requests int) time.Duration {
+	return BudgetInterval(config.Get().Poll.Interval, config.Get().Poll.HourlyBudget, requests)
+}
+
+// BudgetInterval stretches the interval of a loop whose passes take the number of api requests, so it stays within
+// an hourly budget of requests. A budget of 0 leaves the interval as it is.
+func BudgetInterval(interval time.Duration, budget int, requests int) time.Duration {
+	if budget <= 0 {
+		return interval
+	}
+
+	budgetInterval := time.Duration(requests) * time.Hour / time.Duration(budget)
+	return max(interval, budgetInterval)
+}
+
+func (p *implementation) Poll(ctx context.Context) (int, error) {
+	jobs, requests, err := ListQueuedJobs(ctx, p.github)
+
+	// whatever was found before an error is still worth submitting
+	for _, job := range jobs {
+		submitErr := p.dispatcher.Submit(job)
+		if submitErr != nil && !errors.Is(submitErr, dispatcher.ErrDuplicateJob) {
+			err = errors.Join(err, submitErr)
+		}
+	}
+
+	return requests, err
+}
+
+// ListQueuedJobs lists the queued jobs of every repository the installation can access, along with the number of
+// api requests it took
+func ListQueuedJobs(ctx context.Context, githubClient github.Client) ([]*dispatcher.Job, int, error) {
+	// a rate limit wait ends with the context rather than holding up shutdown
+	githubClient = githubClient.WithContext(ctx)
+
+	requests := 1
+	repositories, err := githubClient.ListRepositoriesForAppInstallation(&github.ListRepositoriesForAppInstallationOptions{})
+	if err != nil {
+		return nil, requests, err
+	}
+
+	var jobs []*dispatcher.Job
+	var errs []error
+	for _, repository := range repositories.Repositories {
+		if ctx.Err() != nil {
+			return jobs, requests, ctx.Err()
+		}
+
+		repositoryJobs, repositoryRequests, err := listRepositoryQueuedJobs(githubClient, &repository)
+		jobs = append(jobs, repositoryJobs...)
+		requests += repositoryRequests
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return jobs, requests, errors.Join(errs...)
+}
+
+func listRepositoryQueuedJobs(githubClient github.Client, repository *github.Repository) ([]*dispatcher.Job, int, error) {
+	requests := 0
+	var jobs []*dispatcher.Job
+	for _, status := range workflowRunStatuses {
+		requests++
+		workflowRuns, err := githubClient.ListWorkflowRunsForRepository(&github.ListWorkflowRunsForRepositoryOptions{
+			Username:   repository.Owner.Login,
+			Repository: repository.Name,
+			Status:     status,
+		})
+		if err != nil {
+			return jobs, requests, err
+		}
+
+		for _, workflowRun := range workflowRuns.WorkflowRuns {
+			requests++
+			workflowJobs, err := githubClient.ListJobsForWorkflowRun(&github.ListJobsForWorkflowRunOptions{
+				Username:   repository.Owner.Login,
+				Repository: repository.Name,
+				RunId:      workflowRun.Id,
+			})
+			if err != nil {
+				return jobs, requests, err
+			}
+
+			for _, workflowJob := range workflowJobs.Jobs {
+				if workflowJob.Status != "queued" {
+					continue
+				}
+
+				jobs = append(jobs, dispatcher.NewJobFromWorkflowJob(repository, &workflowJob))
+			}
+		}
+	}
+
+	return jobs, requests, nil
+}
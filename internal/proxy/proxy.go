@@ -0,0 +1,180 @@
+package proxy
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const dialTimeout = 30 * time.Second
+
+// connectPort is the only port CONNECT tunnels to, anything else that speaks tls to an allowed host is not what the
+// allow list was written for
+const connectPort = "443"
+
+// hopHeaders are meant for the proxy itself and are not passed on
+var hopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+type implementation struct {
+	allow     []string
+	transport *http.Transport
+}
+
+// New creates an http proxy that only lets requests through to the hosts on the allow list. Https is tunneled with
+// CONNECT, so the host is all the proxy gets to see of it.
+func New(allow []string) http.Handler {
+	return &implementation{
+		allow: allow,
+		transport: &http.Transport{
+			Proxy:               nil,
+			DialContext:         (&net.Dialer{Timeout: dialTimeout}).DialContext,
+			TLSHandshakeTimeout: dialTimeout,
+		},
+	}
+}
+
+// Allowed reports whether the host matches an entry of the allow list. An entry such as *.github.com matches every
+// subdomain of github.com but not github.com itself, a lone * matches everything. A port on the host is ignored.
+func Allowed(allow []string, host string) bool {
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	for _, entry := range allow {
+		entry = strings.ToLower(entry)
+
+		switch {
+		case entry == "*":
+			return true
+		case strings.HasPrefix(entry, "*."):
+			if strings.HasSuffix(host, entry[1:]) {
+				return true
+			}
+		case entry == host:
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *implementation) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	host := r.URL.Hostname()
+	var port string
+	if r.Method == http.MethodConnect {
+		host, port, _ = net.SplitHostPort(r.Host)
+	}
+
+	logger := log.WithFields(log.Fields{
+		"method": r.Method,
+		"host":   host,
+	})
+
+	if r.Method == http.MethodConnect && port != connectPort {
+		logger.WithField("port", port).Warn("denied tunnel to a port other than 443")
+		http.Error(w, "tunnels are only allowed to port 443", http.StatusForbidden)
+		return
+	}
+
+	if !Allowed(p.allow, host) {
+		logger.Warn("denied request to a host that is not on the allow list")
+		http.Error(w, "host is not on the egress allow list", http.StatusForbidden)
+		return
+	}
+
+	logger.Debug("allowed request")
+
+	if r.Method == http.MethodConnect {
+		p.tunnel(w, r)
+		return
+	}
+
+	p.forward(w, r)
+}
+
+func (p *implementation) tunnel(w http.ResponseWriter, r *http.Request) {
+	upstream, err := net.DialTimeout("tcp", r.Host, dialTimeout)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		upstream.Close()
+		http.Error(w, "tunneling is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	client, _, err := hijacker.Hijack()
+	if err != nil {
+		upstream.Close()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_, err = client.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n"))
+	if err != nil {
+		upstream.Close()
+		client.Close()
+		return
+	}
+
+	go pipe(upstream, client)
+	go pipe(client, upstream)
+}
+
+func pipe(destination net.Conn, source net.Conn) {
+	defer destination.Close()
+	defer source.Close()
+
+	_, _ = io.Copy(destination, source)
+}
+
+func (p *implementation) forward(w http.ResponseWriter, r *http.Request) {
+	if !r.URL.IsAbs() {
+		http.Error(w, "only absolute urls can be proxied", http.StatusBadRequest)
+		return
+	}
+
+	request := r.Clone(r.Context())
+	request.RequestURI = ""
+	for _, header := range hopHeaders {
+		request.Header.Del(header)
+	}
+
+	response, err := p.transport.RoundTrip(request)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer response.Body.Close()
+
+	for _, header := range hopHeaders {
+		response.Header.Del(header)
+	}
+
+	for key, values := range response.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+
+	w.WriteHeader(response.StatusCode)
+	_, _ = io.Copy(w, response.Body)
+}
@@ -0,0 +1,68 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		allow   []string
+		host    string
+		allowed bool
+	}{
+		{"exact host", []string{"example.com"}, "example.com", true},
+		{"exact host in another case", []string{"Example.com"}, "EXAMPLE.com", true},
+		{"exact host with a trailing dot", []string{"example.com"}, "example.com.", true},
+		{"exact host does not match a subdomain", []string{"example.com"}, "api.example.com", false},
+		{"wildcard matches a subdomain", []string{"*.example.com"}, "api.example.com", true},
+		{"wildcard matches a nested subdomain", []string{"*.example.com"}, "a.b.example.com", true},
+		{"wildcard does not match the bare apex", []string{"*.example.com"}, "example.com", false},
+		{"wildcard does not match a lookalike", []string{"*.example.com"}, "evilexample.com", false},
+		{"wildcard does not match a suffix domain", []string{"*.example.com"}, "api.example.com.evil.net", false},
+		{"host with a port", []string{"example.com"}, "example.com:443", true},
+		{"wildcard with a port", []string{"*.example.com"}, "api.example.com:8080", true},
+		{"host not on the list", []string{"example.com"}, "example.org", false},
+		{"lone star matches everything", []string{"*"}, "example.org", true},
+		{"empty allow list", nil, "example.com", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Allowed(test.allow, test.host); got != test.allowed {
+				t.Errorf("Allowed(%v, %q) = %v, expected %v", test.allow, test.host, got, test.allowed)
+			}
+		})
+	}
+}
+
+func TestServeHTTPConnect(t *testing.T) {
+	tests := []struct {
+		name   string
+		host   string
+		status int
+	}{
+		{"host not on the list", "example.org:443", http.StatusForbidden},
+		{"port other than 443", "example.com:22", http.StatusForbidden},
+		{"missing port", "example.com", http.StatusForbidden},
+		// nothing listens on 443 of the loopback, so getting as far as dialing ends in a bad gateway
+		{"allowed host on 443", "127.0.0.1:443", http.StatusBadGateway},
+	}
+
+	proxy := New([]string{"example.com", "127.0.0.1"})
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodConnect, "http://"+test.host, nil)
+			request.Host = test.host
+			recorder := httptest.NewRecorder()
+
+			proxy.ServeHTTP(recorder, request)
+
+			if recorder.Code != test.status {
+				t.Errorf("expected status %d, got %d", test.status, recorder.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,308 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"mirasynth.stream/github-runner/internal/config"
+	"mirasynth.stream/github-runner/internal/dispatcher"
+	"mirasynth.stream/github-runner/internal/github"
+	"mirasynth.stream/github-runner/internal/poller"
+	"mirasynth.stream/github-runner/internal/store"
+)
+
+// Reconciler compares the runners every pool should have with the containers and registrations it actually has,
+// and creates or retires runners until they match. Webhooks get lost and containers crash, the reconciler does not
+// rely on having seen either happen.
+type Reconciler interface {
+	// Reconcile makes a single pass over every pool and returns the number of api requests it took
+	Reconcile(context.Context) (int, error)
+	// Run reconciles right away and then on the configured interval until the context is cancelled, spacing the
+	// passes out so they stay within the hourly request budget
+	Run(context.Context)
+}
+
+type implementation struct {
+	github     github.Client
+	dispatcher dispatcher.Dispatcher
+	store      store.Store
+
+	// idleSince remembers when a runner was first seen idle, GitHub only tells whether it is busy right now
+	idleSince map[string]time.Time
+}
+
+func New(githubClient github.Client, d dispatcher.Dispatcher, s store.Store) Reconciler {
+	return &implementation{
+		github:     githubClient,
+		dispatcher: d,
+		store:      s,
+		idleSince:  map[string]time.Time{},
+	}
+}
+
+func (c *implementation) Run(ctx context.Context) {
+	for {
+		requests, err := c.Reconcile(ctx)
+		if err != nil {
+			log.WithField("component", "reconciler").Error(err)
+		}
+
+		reconcilerConfig := config.Get().Reconciler
+		interval := poller.BudgetInterval(reconcilerConfig.Interval, reconcilerConfig.HourlyBudget, requests)
+		log.WithFields(log.Fields{
+			"component": "reconciler",
+			"requests":  requests,
+			"interval":  interval.String(),
+		}).Debug("reconcile finished")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (c *implementation) Reconcile(ctx context.Context) (int, error) {
+	// the containers are the reality, the state of the dispatcher is brought in line with them first
+	err := c.dispatcher.Adopt(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// a repository that cannot be listed must not hold up the pools, the jobs of the others are still reconciled
+	var errs []error
+	jobs, requests, err := poller.ListQueuedJobs(ctx, c.github)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	queuedByPool := map[string][]*dispatcher.Job{}
+	for _, job := range jobs {
+		pool := dispatcher.MatchPool(job)
+		if pool == nil {
+			continue
+		}
+
+		job.Pool = pool.Name
+		queuedByPool[pool.Name] = append(queuedByPool[pool.Name], job)
+	}
+
+	runnersByPool := map[string][]dispatcher.Runner{}
+	for _, r := range c.dispatcher.Runners() {
+		runnersByPool[r.Pool] = append(runnersByPool[r.Pool], r)
+	}
+
+	seen := map[string]bool{}
+	for i := range config.Get().Pools {
+		pool := &config.Get().Pools[i]
+
+		for _, r := range runnersByPool[pool.Name] {
+			seen[r.Name] = true
+		}
+
+		poolRequests, err := c.reconcilePool(ctx, pool, runnersByPool[pool.Name], queuedByPool[pool.Name])
+		requests += poolRequests
+		if err != nil {
+			errs = append(errs, fmt.Errorf("reconciling pool %q failed, %w", pool.Name, err))
+		}
+	}
+
+	for name := range c.idleSince {
+		if !seen[name] {
+			delete(c.idleSince, name)
+		}
+	}
+
+	return requests, errors.Join(errs...)
+}
+
+// reconcilePool converges the runners of the pool that are not busy, the available ones, on the desired number:
+// one for every queued job plus the warm size, at least enough to reach the minimum size and at most what the
+// maximum size leaves next to the busy runners
+func (c *implementation) reconcilePool(ctx context.Context, pool *config.Pool, runners []dispatcher.Runner, queued []*dispatcher.Job) (int, error) {
+	idle, busy, requests, err := c.runnerStates(runners)
+	if err != nil {
+		return requests, err
+	}
+
+	available := len(runners) - busy
+	desired := max(len(queued)+pool.WarmSize, pool.MinSize-busy)
+	if pool.MaxSize > 0 {
+		desired = min(desired, pool.MaxSize-busy)
+	}
+
+	logger := log.WithFields(log.Fields{
+		"component": "reconciler",
+		"pool":      pool.Name,
+		"queued":    len(queued),
+		"busy":      busy,
+		"available": available,
+		"desired":   desired,
+	})
+	logger.Debug("reconciling pool")
+
+	switch {
+	case available > desired:
+		return requests, c.scaleDown(ctx, pool, idle, available-desired)
+	case available < desired:
+		return requests, c.scaleUp(ctx, logger, pool, runners, queued, desired-available)
+	}
+
+	return requests, nil
+}
+
+// scaleDown retires up to surplus runners that have been idle for longer than the idle timeout
+func (c *implementation) scaleDown(ctx context.Context, pool *config.Pool, idle []dispatcher.Runner, surplus int) error {
+	if pool.IdleTimeout == 0 {
+		return nil
+	}
+
+	// runners that were started for a job another runner took go first, then the ones idle for the longest
+	sort.SliceStable(idle, func(i, j int) bool {
+		if (idle[i].JobId == 0) != (idle[j].JobId == 0) {
+			return idle[i].JobId != 0
+		}
+		return c.idleSince[idle[i].Name].Before(c.idleSince[idle[j].Name])
+	})
+
+	for _, r := range idle {
+		if surplus == 0 {
+			break
+		}
+
+		if time.Since(c.idleSince[r.Name]) < pool.IdleTimeout {
+			continue
+		}
+
+		err := c.dispatcher.Retire(ctx, r.Name)
+		if err != nil {
+			return err
+		}
+
+		surplus--
+	}
+
+	return nil
+}
+
+// scaleUp dispatches runners for the queued jobs that have none, the webhook or poller either missed them or their
+// runner died, and fills the rest of the deficit with warm runners
+func (c *implementation) scaleUp(ctx context.Context, logger *log.Entry, pool *config.Pool, runners []dispatcher.Runner, queued []*dispatcher.Job, deficit int) error {
+	gracePeriod := config.Get().Reconciler.QueueGracePeriod
+
+	for _, job := range queued {
+		if deficit == 0 {
+			return nil
+		}
+
+		// a freshly queued job is still on its way through the webhook
+		if hasRunner(runners, job.Id) || time.Since(job.CreatedAt) < gracePeriod {
+			continue
+		}
+
+		logger.WithField("job", job.Id).Info("dispatching runner for a queued job without one")
+
+		err := c.dispatch(ctx, job)
+		if err != nil {
+			return err
+		}
+
+		deficit--
+	}
+
+	warm := 0
+	for _, r := range runners {
+		if r.JobId == 0 {
+			warm++
+		}
+	}
+
+	for ; deficit > 0 && warm < pool.WarmSize; warm++ {
+		logger.Info("provisioning warm runner")
+
+		err := c.dispatcher.Provision(ctx, pool)
+		if err != nil {
+			return err
+		}
+
+		deficit--
+	}
+
+	return nil
+}
+
+// dispatch starts a runner for a queued job that has none. Submit would turn down a job whose runner died, since the
+// job is claimed already, so the job is claimed here and dispatched either way. The claim keeps a webhook, poll or
+// recovery of the job that arrives afterwards from starting a second runner.
+func (c *implementation) dispatch(ctx context.Context, job *dispatcher.Job) error {
+	claimed, err := c.store.Claim(config.Get().GitHub.Webhook.DeduplicationTtl, dispatcher.JobKey(job.Id))
+	if err != nil {
+		return err
+	}
+
+	err = c.dispatcher.Dispatch(ctx, job)
+	if err != nil && claimed {
+		// let the next pass, or a delivery of the job, try again
+		return errors.Join(err, c.store.Release(dispatcher.JobKey(job.Id)))
+	}
+
+	return err
+}
+
+func hasRunner(runners []dispatcher.Runner, jobId int64) bool {
+	for _, r := range runners {
+		if r.JobId == jobId {
+			return true
+		}
+	}
+
+	return false
+}
+
+// runnerStates returns the runners GitHub lists as registered but not busy, the number of busy ones and the number of
+// api requests it took. Runners that have not registered yet are still starting and count as neither.
+func (c *implementation) runnerStates(runners []dispatcher.Runner) ([]dispatcher.Runner, int, int, error) {
+	registered := map[string]map[string]github.Runner{}
+	var idle []dispatcher.Runner
+	busy := 0
+
+	for _, r := range runners {
+		registration := r.Registration()
+		key := registration.String()
+
+		if _, ok := registered[key]; !ok {
+			registeredRunners, err := registration.ListRunners(c.github)
+			if err != nil {
+				return nil, 0, len(registered), err
+			}
+
+			registered[key] = map[string]github.Runner{}
+			for _, runner := range registeredRunners {
+				registered[key][runner.Name] = runner
+			}
+		}
+
+		runner, ok := registered[key][r.Name]
+		if !ok || runner.Busy {
+			if runner.Busy {
+				busy++
+			}
+
+			delete(c.idleSince, r.Name)
+			continue
+		}
+
+		if _, ok := c.idleSince[r.Name]; !ok {
+			c.idleSince[r.Name] = time.Now()
+		}
+
+		idle = append(idle, r)
+	}
+
+	return idle, busy, len(registered), nil
+}
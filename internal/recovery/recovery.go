@@ -0,0 +1,171 @@
+package recovery
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"mirasynth.stream/github-runner/internal/config"
+	"mirasynth.stream/github-runner/internal/dispatcher"
+	"mirasynth.stream/github-runner/internal/github"
+	"mirasynth.stream/github-runner/internal/github/events"
+	"mirasynth.stream/github-runner/internal/metrics"
+	"mirasynth.stream/github-runner/internal/store"
+)
+
+const cursorKey = "recovery:cursor"
+
+// Recoverer replays workflow_job deliveries that never made it to the webhook server, using the delivery log GitHub
+// keeps for the app webhook
+type Recoverer interface {
+	Recover(context.Context) error
+	// Run recovers once straight away and then on the configured interval, until the context is cancelled
+	Run(context.Context)
+}
+
+type implementation struct {
+	github     github.Client
+	dispatcher dispatcher.Dispatcher
+	store      store.Store
+}
+
+func New(githubClient github.Client, d dispatcher.Dispatcher, s store.Store) Recoverer {
+	return &implementation{
+		github:     githubClient,
+		dispatcher: d,
+		store:      s,
+	}
+}
+
+func (r *implementation) Run(ctx context.Context) {
+	ticker := time.NewTicker(config.Get().GitHub.Webhook.Recovery.Interval)
+	defer ticker.Stop()
+
+	for {
+		err := r.Recover(ctx)
+		if err != nil {
+			log.WithField("component", "recovery").Error(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *implementation) Recover(ctx context.Context) error {
+	var cursor int64
+	storedCursor, ok := r.store.Get(cursorKey)
+	if ok {
+		cursor, _ = strconv.ParseInt(storedCursor, 10, 64)
+	}
+
+	// deliveries older than the lookback are never replayed, so there is no point in paging through them
+	oldest := time.Now().Add(-config.Get().GitHub.Webhook.Recovery.Lookback)
+	deliveries, err := r.github.ListDeliveriesForAppWebhook(&github.ListDeliveriesForAppWebhookOptions{
+		Since:          cursor,
+		DeliveredAfter: oldest,
+	})
+	if err != nil {
+		return err
+	}
+
+	// deliveries are listed newest first, replay them in the order they happened
+	slices.SortFunc(*deliveries, func(a github.HookDelivery, b github.HookDelivery) int {
+		return cmp.Compare(a.Id, b.Id)
+	})
+
+	for _, delivery := range *deliveries {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		_, processed := r.store.Get(dispatcher.DeliveryKey(delivery.Guid))
+		recoverable := delivery.Event == string(events.EventWorkflowJob) &&
+			delivery.Action == string(events.ActionQueued)
+
+		// a delivery that keeps failing must not hold back the ones after it
+		if recoverable && !processed {
+			err = r.recoverDelivery(&delivery)
+			if err != nil {
+				metrics.RecoveryFailures.Add(1)
+				log.WithFields(log.Fields{
+					"component": "recovery",
+					"delivery":  delivery.Guid,
+				}).Error(err)
+			}
+		}
+
+		cursor = delivery.Id
+		err = r.store.Set(cursorKey, strconv.FormatInt(cursor, 10), 0)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *implementation) recoverDelivery(delivery *github.HookDelivery) error {
+	logger := log.WithFields(log.Fields{
+		"component": "recovery",
+		"delivery":  delivery.Guid,
+	})
+
+	failed := delivery.StatusCode < 200 || delivery.StatusCode >= 300
+	if failed && config.Get().GitHub.Webhook.Recovery.Redeliver {
+		logger.Info("requesting redelivery of failed delivery")
+		_, err := r.github.RedeliverDeliveryForAppWebhook(&github.RedeliverDeliveryForAppWebhookOptions{
+			DeliveryId: delivery.Id,
+		})
+		return err
+	}
+
+	fullDelivery, err := r.github.GetDeliveryForAppWebhook(&github.GetDeliveryForAppWebhookOptions{
+		DeliveryId: delivery.Id,
+	})
+	if err != nil {
+		return err
+	}
+
+	var event events.WorkflowJobEvent
+	err = json.Unmarshal(fullDelivery.Request.Payload, &event)
+	if err != nil {
+		return err
+	}
+
+	job := dispatcher.NewJobFromEvent(delivery.Guid, &event)
+	if job == nil {
+		return nil
+	}
+
+	// the job may well have been picked up, or cancelled, while the delivery was missing
+	workflowJob, err := r.github.GetJobForWorkflowRun(&github.GetJobForWorkflowRunOptions{
+		Username:   job.Owner,
+		Repository: job.Repository,
+		JobId:      job.Id,
+	})
+	if err != nil {
+		return err
+	}
+
+	if workflowJob.Status != string(events.ActionQueued) {
+		logger.WithField("job", job.Id).Debug("job is no longer queued, skipping delivery")
+		return nil
+	}
+
+	logger.WithField("job", job.Id).Info("replaying missed delivery")
+	err = r.dispatcher.Submit(job)
+	if errors.Is(err, dispatcher.ErrDuplicateJob) {
+		return nil
+	}
+
+	return err
+}
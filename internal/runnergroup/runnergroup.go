@@ -0,0 +1,170 @@
+package runnergroup
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	log "github.com/sirupsen/logrus"
+	"mirasynth.stream/github-runner/internal/config"
+	"mirasynth.stream/github-runner/internal/github"
+)
+
+// Find returns the runner group of the organization with the name, or nil when there is none
+func Find(githubClient github.Client, organization string, name string) (*github.RunnerGroup, error) {
+	response, err := githubClient.ListSelfHostedRunnerGroupsForOrganization(&github.ListSelfHostedRunnerGroupsForOrganizationOptions{
+		Organization: organization,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, group := range response.RunnerGroups {
+		if group.Name == name {
+			return &group, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// Id returns the id of the runner group of the organization with the name, a missing group is an error
+func Id(githubClient github.Client, organization string, name string) (int, error) {
+	group, err := Find(githubClient, organization, name)
+	if err != nil {
+		return 0, err
+	}
+
+	if group == nil {
+		return 0, fmt.Errorf("runner group %q does not exist in organization %q", name, organization)
+	}
+
+	return group.Id, nil
+}
+
+// Sync creates the declared runner groups that are missing and brings the visibility and repository access of every
+// group in line with its declaration. Groups that are not declared are left alone.
+func Sync(githubClient github.Client, groups []config.RunnerGroup) error {
+	if len(groups) == 0 {
+		return nil
+	}
+
+	repositories, err := githubClient.ListRepositoriesForAppInstallation(&github.ListRepositoriesForAppInstallationOptions{})
+	if err != nil {
+		return err
+	}
+
+	repositoryIds := map[string]int{}
+	for _, repository := range repositories.Repositories {
+		repositoryIds[repository.FullName] = repository.Id
+	}
+
+	var errs []error
+	for _, group := range groups {
+		err = syncGroup(githubClient, &group, repositoryIds)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("runner group %q of organization %q: %w", group.Name, group.Organization, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func syncGroup(githubClient github.Client, group *config.RunnerGroup, repositoryIds map[string]int) error {
+	logger := log.WithFields(log.Fields{
+		"component":    "runnergroup",
+		"organization": group.Organization,
+		"runnerGroup":  group.Name,
+	})
+
+	// the ids come from the installation, a repository it cannot see could not run our runners anyway
+	var selectedIds []int
+	for _, repository := range group.Repositories {
+		id, ok := repositoryIds[fmt.Sprintf("%s/%s", group.Organization, repository)]
+		if !ok {
+			return fmt.Errorf("repository %q is not accessible to the installation", repository)
+		}
+
+		selectedIds = append(selectedIds, id)
+	}
+	slices.Sort(selectedIds)
+
+	existing, err := Find(githubClient, group.Organization, group.Name)
+	if err != nil {
+		return err
+	}
+
+	if existing == nil {
+		logger.Info("creating runner group")
+
+		_, err = githubClient.CreateSelfHostedRunnerGroupForOrganization(&github.CreateSelfHostedRunnerGroupForOrganizationOptions{
+			Organization: group.Organization,
+			RequestData: &github.CreateSelfHostedRunnerGroupForOrganizationRequest{
+				Name:                     group.Name,
+				Visibility:               string(group.Visibility),
+				SelectedRepositoryIds:    selectedIds,
+				AllowsPublicRepositories: group.AllowsPublicRepositories,
+			},
+		})
+		return err
+	}
+
+	if existing.Visibility != string(group.Visibility) || existing.AllowsPublicRepositories != group.AllowsPublicRepositories {
+		logger.WithFields(log.Fields{
+			"visibility":               group.Visibility,
+			"allowsPublicRepositories": group.AllowsPublicRepositories,
+		}).Info("updating runner group")
+
+		_, err = githubClient.UpdateSelfHostedRunnerGroupForOrganization(&github.UpdateSelfHostedRunnerGroupForOrganizationOptions{
+			Organization:  group.Organization,
+			RunnerGroupId: existing.Id,
+			RequestData: &github.UpdateSelfHostedRunnerGroupForOrganizationRequest{
+				Name:                     group.Name,
+				Visibility:               string(group.Visibility),
+				AllowsPublicRepositories: group.AllowsPublicRepositories,
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if group.Visibility != config.RunnerGroupVisibilitySelected {
+		return nil
+	}
+
+	access, err := githubClient.ListRepositoryAccessToSelfHostedRunnerGroupInOrganization(&github.ListRepositoryAccessToSelfHostedRunnerGroupInOrganizationOptions{
+		Organization:  group.Organization,
+		RunnerGroupId: existing.Id,
+	})
+	if err != nil {
+		return err
+	}
+
+	var currentIds []int
+	for _, repository := range access.Repositories {
+		currentIds = append(currentIds, repository.Id)
+	}
+	slices.Sort(currentIds)
+
+	if slices.Equal(currentIds, selectedIds) {
+		logger.Debug("runner group is in sync")
+		return nil
+	}
+
+	logger.WithField("repositories", group.Repositories).Info("setting repository access of runner group")
+
+	// an empty list has to go out as [] rather than null to take every repository away
+	if selectedIds == nil {
+		selectedIds = []int{}
+	}
+
+	_, err = githubClient.SetRepositoryAccessForSelfHostedRunnerGroupInOrganization(&github.SetRepositoryAccessForSelfHostedRunnerGroupInOrganizationOptions{
+		Organization:  group.Organization,
+		RunnerGroupId: existing.Id,
+		RequestData: &github.SetRepositoryAccessForSelfHostedRunnerGroupInOrganizationRequest{
+			SelectedRepositoryIds: selectedIds,
+		},
+	})
+	return err
+}
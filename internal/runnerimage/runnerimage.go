@@ -0,0 +1,166 @@
+package runnerimage
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"mirasynth.stream/github-runner/assets"
+	"mirasynth.stream/github-runner/internal/atlas"
+	"mirasynth.stream/github-runner/internal/config"
+	"mirasynth.stream/github-runner/internal/container"
+)
+
+type BuildOptions struct {
+	// Version is the version of the actions runner, the Checksum is the sha256 of its linux x64 release archive
+	Version  string   `json:"version"`
+	Checksum string   `json:"checksum"`
+	Tags     []string `json:"tags"`
+}
+
+// Tag returns the tag of the runner image for a runner version
+func Tag(version string) string {
+	return fmt.Sprintf("%s:%s", atlas.RUNNER_IMAGE_REPOSITORY, version)
+}
+
+// Build builds the runner image from the embedded assets, it is tagged with the runner version on top of the tags in
+// the options
+func Build(ctx context.Context, containerClient container.Container, options *BuildOptions) error {
+	buildContext, err := newBuildContext()
+	if err != nil {
+		return err
+	}
+
+	return containerClient.Build(ctx, &container.BuildOptions{
+		Context:    buildContext,
+		Dockerfile: "Dockerfile",
+		Tags:       append([]string{Tag(options.Version)}, options.Tags...),
+		BuildArgs: map[string]string{
+			"GITHUB_RUNNER_VERSION": options.Version,
+			"GITHUB_RUNNER_SHASUM":  options.Checksum,
+		},
+	})
+}
+
+// EnsureBuilt builds the runner image of the configured version unless it is present already, the default runner
+// image is tagged along so pools that do not name an image pick it up
+func EnsureBuilt(ctx context.Context, containerClient container.Container) error {
+	runner := config.Get().Runner
+
+	info, err := containerClient.InspectImage(ctx, Tag(runner.Version))
+	if err != nil || info != nil {
+		return err
+	}
+
+	return Build(ctx, containerClient, &BuildOptions{
+		Version:  runner.Version,
+		Checksum: runner.Checksum,
+		Tags:     []string{atlas.DEFAULT_RUNNER_IMAGE},
+	})
+}
+
+// newBuildContext packs the embedded runner assets into the tar archive the docker daemon builds from
+func newBuildContext() (*bytes.Buffer, error) {
+	runnerAssets, err := fs.Sub(assets.Runner, "runner")
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := &bytes.Buffer{}
+	writer := tar.NewWriter(buffer)
+
+	err = fs.WalkDir(runnerAssets, ".", func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		content, err := fs.ReadFile(runnerAssets, path)
+		if err != nil {
+			return err
+		}
+
+		err = writer.WriteHeader(&tar.Header{
+			Name: path,
+			Mode: 0o644,
+			Size: int64(len(content)),
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = writer.Write(content)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer, writer.Close()
+}
+
+// Version returns the runner version an image was built with, or an empty string for images that were not built
+// from the embedded assets
+func Version(ctx context.Context, containerClient container.Container, imageName string) (string, error) {
+	info, err := containerClient.InspectImage(ctx, imageName)
+	if err != nil || info == nil {
+		return "", err
+	}
+
+	return info.Labels[atlas.IMAGE_LABEL_RUNNER_VERSION], nil
+}
+
+// CheckVersion reports whether the image carries a runner older than the configured minimum version, and logs a
+// warning if so. GitHub does not report the version of a registered runner, the label of its image is the only
+// place to learn it from.
+func CheckVersion(ctx context.Context, containerClient container.Container, imageName string) (bool, error) {
+	minimumVersion := config.Get().Runner.MinimumVersion
+	if minimumVersion == "" {
+		return false, nil
+	}
+
+	version, err := Version(ctx, containerClient, imageName)
+	if err != nil || version == "" {
+		return false, err
+	}
+
+	if !Outdated(version, minimumVersion) {
+		return false, nil
+	}
+
+	log.WithFields(log.Fields{
+		"image":          imageName,
+		"version":        version,
+		"minimumVersion": minimumVersion,
+	}).Warn("runner image is older than the minimum runner version")
+
+	return true, nil
+}
+
+// Outdated compares two dotted versions such as 2.316.1 number by number
+func Outdated(version string, minimumVersion string) bool {
+	versionParts := strings.Split(version, ".")
+	minimumParts := strings.Split(minimumVersion, ".")
+
+	for i := 0; i < max(len(versionParts), len(minimumParts)); i++ {
+		current, minimum := versionPart(versionParts, i), versionPart(minimumParts, i)
+		if current != minimum {
+			return current < minimum
+		}
+	}
+
+	return false
+}
+
+func versionPart(parts []string, i int) int {
+	if i >= len(parts) {
+		return 0
+	}
+
+	part, _ := strconv.Atoi(parts[i])
+	return part
+}
@@ -2,11 +2,12 @@ package github
 
 import (
 	"github.com/gin-gonic/gin"
+	"mirasynth.stream/github-runner/internal/dispatcher"
 	"mirasynth.stream/github-runner/internal/server/github/webhook"
 )
 
-func RegisterController(routerGroup *gin.RouterGroup) {
+func RegisterController(routerGroup *gin.RouterGroup, d dispatcher.Dispatcher) {
 	githubRouterGroup := routerGroup.Group("/github")
 
-	webhook.RegisterController(githubRouterGroup)
+	webhook.RegisterController(githubRouterGroup, d)
 }
@@ -0,0 +1,165 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"mirasynth.stream/github-runner/internal/github/events"
+)
+
+const payloadContextKey = "github.webhook.payload"
+
+// ErrDuplicateDelivery is returned by a handler that recognised a delivery it has already processed
+var ErrDuplicateDelivery = errors.New("the delivery has already been processed")
+
+// Delivery describes a single webhook delivery, independently of the event payload it carries
+type Delivery struct {
+	Id      string
+	Event   events.EventType
+	Action  events.Action
+	Payload []byte
+}
+
+type handlerFunc func(context.Context, *Delivery) error
+
+// Router dispatches webhook deliveries to the handlers registered for their X-GitHub-Event header and payload action
+type Router struct {
+	handlers map[events.EventType]map[events.Action][]handlerFunc
+}
+
+func NewRouter() *Router {
+	return &Router{
+		handlers: map[events.EventType]map[events.Action][]handlerFunc{},
+	}
+}
+
+// Handle registers a handler for an event and action, events.ActionAny matches every action of the event. The
+// payload is decoded into T before the handler is called.
+func Handle[T any](router *Router, event events.EventType, action events.Action, handler func(context.Context, *Delivery, *T) error) {
+	if router.handlers[event] == nil {
+		router.handlers[event] = map[events.Action][]handlerFunc{}
+	}
+
+	router.handlers[event][action] = append(router.handlers[event][action], func(ctx context.Context, delivery *Delivery) error {
+		var payload T
+		err := json.Unmarshal(delivery.Payload, &payload)
+		if err != nil {
+			return fmt.Errorf("could not parse %s payload, %s", delivery.Event, err)
+		}
+
+		return handler(ctx, delivery, &payload)
+	})
+}
+
+// Route calls every handler registered for the delivery and reports whether any handler was found
+func (r *Router) Route(ctx context.Context, delivery *Delivery) (bool, error) {
+	handlersForEvent, ok := r.handlers[delivery.Event]
+	if !ok {
+		return false, nil
+	}
+
+	handlers := handlersForEvent[events.ActionAny]
+	if delivery.Action != events.ActionAny {
+		handlers = slices.Concat(handlersForEvent[delivery.Action], handlers)
+	}
+
+	for _, handler := range handlers {
+		err := handler(ctx, delivery)
+		if err != nil {
+			return true, err
+		}
+	}
+
+	return len(handlers) > 0, nil
+}
+
+// Handler returns the gin handler that turns a request into a Delivery and routes it
+func (r *Router) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		payload, err := getPayload(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		var event events.Event
+		err = json.Unmarshal(payload, &event)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("could not parse payload, %s", err),
+			})
+			return
+		}
+
+		delivery := &Delivery{
+			Id:      c.GetHeader("X-GitHub-Delivery"),
+			Event:   events.EventType(c.GetHeader("X-GitHub-Event")),
+			Action:  event.Action,
+			Payload: payload,
+		}
+
+		handled, err := r.Route(c.Request.Context(), delivery)
+		if errors.Is(err, ErrDuplicateDelivery) {
+			log.WithFields(log.Fields{
+				"delivery": delivery.Id,
+				"event":    delivery.Event,
+				"action":   delivery.Action,
+			}).Info("ignoring duplicate delivery")
+			c.Status(http.StatusOK)
+			return
+		}
+
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		if !handled {
+			c.Status(http.StatusNoContent)
+			return
+		}
+
+		c.Status(http.StatusAccepted)
+	}
+}
+
+// payloadMiddleware reads the request body once so the signature check and the router can share it
+func payloadMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer c.Request.Body.Close()
+
+		payload, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("cannot read the request body: %s", err),
+			})
+			return
+		}
+
+		c.Set(payloadContextKey, payload)
+		c.Request.Body = io.NopCloser(bytes.NewReader(payload))
+
+		c.Next()
+	}
+}
+
+func getPayload(c *gin.Context) ([]byte, error) {
+	payload, ok := c.Get(payloadContextKey)
+	if !ok {
+		return nil, fmt.Errorf("the request payload has not been read")
+	}
+
+	return payload.([]byte), nil
+}
@@ -0,0 +1,59 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"mirasynth.stream/github-runner/internal/github/events"
+)
+
+func TestRouterHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// the handler treats every delivery after the first one with the same id as a duplicate
+	seen := map[string]bool{}
+	router := NewRouter()
+	Handle(router, events.EventWorkflowJob, events.ActionQueued, func(_ context.Context, delivery *Delivery, _ *events.WorkflowJobEvent) error {
+		if seen[delivery.Id] {
+			return ErrDuplicateDelivery
+		}
+		seen[delivery.Id] = true
+		return nil
+	})
+
+	engine := gin.New()
+	engine.POST("/webhook", payloadMiddleware(), router.Handler())
+
+	tests := []struct {
+		name     string
+		event    events.EventType
+		delivery string
+		payload  string
+		status   int
+	}{
+		{"first delivery", events.EventWorkflowJob, "delivery-1", `{"action":"queued"}`, http.StatusAccepted},
+		// github retries a delivery that did not get a 2xx, so a duplicate must not look like a failure
+		{"duplicate delivery", events.EventWorkflowJob, "delivery-1", `{"action":"queued"}`, http.StatusOK},
+		{"event without a handler", events.EventPing, "delivery-2", `{"zen":"hello"}`, http.StatusNoContent},
+		{"invalid payload", events.EventWorkflowJob, "delivery-3", `not json`, http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(test.payload))
+			request.Header.Set("X-GitHub-Event", string(test.event))
+			request.Header.Set("X-GitHub-Delivery", test.delivery)
+			recorder := httptest.NewRecorder()
+
+			engine.ServeHTTP(recorder, request)
+
+			if recorder.Code != test.status {
+				t.Errorf("expected status %d, got %d", test.status, recorder.Code)
+			}
+		})
+	}
+}
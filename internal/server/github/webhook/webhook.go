@@ -1,29 +1,63 @@
 package webhook
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"io"
+	"hash"
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
 	"mirasynth.stream/github-runner/internal/config"
+	"mirasynth.stream/github-runner/internal/dispatcher"
+	"mirasynth.stream/github-runner/internal/github/events"
 )
 
-func RegisterController(routerGroup *gin.RouterGroup) {
-	webhookRouterGroup := routerGroup.Group("/webhook", verifySignatureMiddleware())
+func RegisterController(routerGroup *gin.RouterGroup, d dispatcher.Dispatcher) {
+	webhookRouterGroup := routerGroup.Group("/webhook", payloadMiddleware(), verifySignatureMiddleware())
 
-	webhookRouterGroup.POST("/webhook", func(c *gin.Context) {
-		// do stuff
+	router := NewRouter()
+
+	Handle(router, events.EventPing, events.ActionAny, func(_ context.Context, delivery *Delivery, event *events.PingEvent) error {
+		log.WithFields(log.Fields{
+			"delivery": delivery.Id,
+			"hook":     event.HookId,
+		}).Info(event.Zen)
+		return nil
+	})
+
+	Handle(router, events.EventWorkflowJob, events.ActionQueued, func(_ context.Context, delivery *Delivery, event *events.WorkflowJobEvent) error {
+		job := dispatcher.NewJobFromEvent(delivery.Id, event)
+		if job == nil {
+			return nil
+		}
+
+		err := d.Submit(job)
+		if errors.Is(err, dispatcher.ErrDuplicateJob) {
+			return ErrDuplicateDelivery
+		}
+
+		return err
 	})
+
+	webhookRouterGroup.POST("/webhook", router.Handler())
 }
 
 func verifySignatureMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		err := verifySignature(c.Request, config.GetGitHubWebhookSecret())
+		webhookConfig := config.Get().GitHub.Webhook
+
+		payload, err := getPayload(c)
+		if err == nil {
+			err = verifySignature(c.Request.Header, payload, webhookConfig.GetSecrets(), webhookConfig.RequireSha256)
+		}
 
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
@@ -36,30 +70,54 @@ func verifySignatureMiddleware() gin.HandlerFunc {
 	}
 }
 
-// original code snippet from https://stackoverflow.com/questions/53242837/validating-github-webhook-hmac-signature-in-go
-// modified to return errors instead of logging them
-func verifySignature(request *http.Request, key string) error {
-	// Assuming a non-empty header
-	gotHash := strings.SplitN(request.Header.Get("X-Hub-Signature"), "=", 2)
-	if gotHash[0] != "sha1" {
-		return fmt.Errorf("the X-Hub-Signature header contains invalid data")
+// verifySignature checks the payload against the X-Hub-Signature-256 header, falling back to the legacy sha1
+// X-Hub-Signature header unless requireSha256 is set. The signature is accepted if it matches any of the keys, which
+// allows the webhook secret to be rotated without downtime.
+func verifySignature(header http.Header, payload []byte, keys []string, requireSha256 bool) error {
+	// an empty key would let anyone sign a delivery
+	keys = slices.DeleteFunc(slices.Clone(keys), func(key string) bool {
+		return key == ""
+	})
+	if len(keys) == 0 {
+		return fmt.Errorf("no webhook secrets have been configured")
 	}
-	defer request.Body.Close()
 
-	b, err := io.ReadAll(request.Body)
-	if err != nil {
-		return fmt.Errorf("cannot read the request body: %s", err)
+	if signature := header.Get("X-Hub-Signature-256"); signature != "" {
+		return verifyHmac(sha256.New, "sha256", "X-Hub-Signature-256", signature, payload, keys)
 	}
 
-	hash := hmac.New(sha1.New, []byte(key))
-	if _, err := hash.Write(b); err != nil {
-		return fmt.Errorf("cannot compute the HMAC for request: %s", err)
+	if requireSha256 {
+		return fmt.Errorf("the X-Hub-Signature-256 header is missing, sha1 only deliveries are not accepted")
 	}
 
-	expectedHash := hex.EncodeToString(hash.Sum(nil))
-	if gotHash[1] != expectedHash {
-		return fmt.Errorf("signatures do not match")
+	if signature := header.Get("X-Hub-Signature"); signature != "" {
+		return verifyHmac(sha1.New, "sha1", "X-Hub-Signature", signature, payload, keys)
+	}
+
+	return fmt.Errorf("the request is not signed")
+}
+
+func verifyHmac(hashFunc func() hash.Hash, algorithm string, headerName string, signature string, payload []byte, keys []string) error {
+	gotAlgorithm, gotHash, ok := strings.Cut(signature, "=")
+	if !ok || gotAlgorithm != algorithm {
+		return fmt.Errorf("the %s header contains invalid data", headerName)
+	}
+
+	gotHashBytes, err := hex.DecodeString(gotHash)
+	if err != nil {
+		return fmt.Errorf("the %s header contains invalid data", headerName)
+	}
+
+	for _, key := range keys {
+		mac := hmac.New(hashFunc, []byte(key))
+		if _, err := mac.Write(payload); err != nil {
+			return fmt.Errorf("cannot compute the HMAC for request: %s", err)
+		}
+
+		if hmac.Equal(gotHashBytes, mac.Sum(nil)) {
+			return nil
+		}
 	}
 
-	return nil
+	return fmt.Errorf("signatures do not match")
 }
@@ -0,0 +1,123 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"net/http"
+	"testing"
+)
+
+func sign(hashFunc func() hash.Hash, algorithm string, key string, payload []byte) string {
+	mac := hmac.New(hashFunc, []byte(key))
+	mac.Write(payload)
+	return algorithm + "=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifySignature(t *testing.T) {
+	payload := []byte(`{"action":"queued"}`)
+
+	tests := []struct {
+		name          string
+		headers       map[string]string
+		keys          []string
+		requireSha256 bool
+		valid         bool
+	}{
+		{
+			name:    "sha256",
+			headers: map[string]string{"X-Hub-Signature-256": sign(sha256.New, "sha256", "secret", payload)},
+			keys:    []string{"secret"},
+			valid:   true,
+		},
+		{
+			name:    "sha256 with the wrong key",
+			headers: map[string]string{"X-Hub-Signature-256": sign(sha256.New, "sha256", "other", payload)},
+			keys:    []string{"secret"},
+		},
+		{
+			name:    "sha1",
+			headers: map[string]string{"X-Hub-Signature": sign(sha1.New, "sha1", "secret", payload)},
+			keys:    []string{"secret"},
+			valid:   true,
+		},
+		{
+			name:          "sha1 when sha256 is required",
+			headers:       map[string]string{"X-Hub-Signature": sign(sha1.New, "sha1", "secret", payload)},
+			keys:          []string{"secret"},
+			requireSha256: true,
+		},
+		{
+			name: "sha256 is checked before sha1",
+			headers: map[string]string{
+				"X-Hub-Signature-256": sign(sha256.New, "sha256", "other", payload),
+				"X-Hub-Signature":     sign(sha1.New, "sha1", "secret", payload),
+			},
+			keys: []string{"secret"},
+		},
+		{
+			name:    "old secret during a rotation",
+			headers: map[string]string{"X-Hub-Signature-256": sign(sha256.New, "sha256", "old", payload)},
+			keys:    []string{"new", "old"},
+			valid:   true,
+		},
+		{
+			name:    "new secret during a rotation",
+			headers: map[string]string{"X-Hub-Signature-256": sign(sha256.New, "sha256", "new", payload)},
+			keys:    []string{"new", "old"},
+			valid:   true,
+		},
+		{
+			name:    "empty secret",
+			headers: map[string]string{"X-Hub-Signature-256": sign(sha256.New, "sha256", "", payload)},
+			keys:    []string{""},
+		},
+		{
+			name:    "empty secret next to a real one",
+			headers: map[string]string{"X-Hub-Signature-256": sign(sha256.New, "sha256", "", payload)},
+			keys:    []string{"", "secret"},
+		},
+		{
+			name:    "no secrets",
+			headers: map[string]string{"X-Hub-Signature-256": sign(sha256.New, "sha256", "secret", payload)},
+		},
+		{
+			name: "unsigned",
+			keys: []string{"secret"},
+		},
+		{
+			name:    "algorithm does not match the header",
+			headers: map[string]string{"X-Hub-Signature-256": sign(sha1.New, "sha1", "secret", payload)},
+			keys:    []string{"secret"},
+		},
+		{
+			name:    "missing algorithm",
+			headers: map[string]string{"X-Hub-Signature-256": "0123abcd"},
+			keys:    []string{"secret"},
+		},
+		{
+			name:    "hash is not hex",
+			headers: map[string]string{"X-Hub-Signature-256": "sha256=not-hex"},
+			keys:    []string{"secret"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			header := http.Header{}
+			for name, value := range test.headers {
+				header.Set(name, value)
+			}
+
+			err := verifySignature(header, payload, test.keys, test.requireSha256)
+			if test.valid && err != nil {
+				t.Errorf("expected the signature to be accepted, got %v", err)
+			}
+			if !test.valid && err == nil {
+				t.Error("expected the signature to be rejected")
+			}
+		})
+	}
+}
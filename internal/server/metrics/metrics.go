@@ -0,0 +1,11 @@
+package metrics
+
+import (
+	"expvar"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterController(routerGroup *gin.RouterGroup) {
+	routerGroup.GET("/metrics", gin.WrapH(expvar.Handler()))
+}
@@ -2,17 +2,20 @@ package server
 
 import (
 	"github.com/gin-gonic/gin"
+	"mirasynth.stream/github-runner/internal/dispatcher"
 	"mirasynth.stream/github-runner/internal/server/github"
 	"mirasynth.stream/github-runner/internal/server/health"
+	"mirasynth.stream/github-runner/internal/server/metrics"
 )
 
-func StartServer() {
+func StartServer(d dispatcher.Dispatcher) {
 	ginEngine := gin.Default()
 
 	routerGroup := ginEngine.Group("/api/v1")
 
 	health.RegisterController(routerGroup)
-	github.RegisterController(routerGroup)
+	github.RegisterController(routerGroup, d)
+	metrics.RegisterController(routerGroup)
 
 	ginEngine.Run(":3038")
 }
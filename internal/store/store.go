@@ -0,0 +1,153 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path"
+	"sync"
+	"time"
+)
+
+type entry struct {
+	Value     string    `json:"value"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+func (e entry) expired(now time.Time) bool {
+	return !e.ExpiresAt.IsZero() && now.After(e.ExpiresAt)
+}
+
+// Store is a small persistent key value store where every key can expire after a TTL
+type Store interface {
+	Get(key string) (string, bool)
+	// Set stores the value under key, a ttl of 0 keeps the key forever
+	Set(key string, value string, ttl time.Duration) error
+	// Claim records all the keys at once and reports false, without recording anything, if any of them already exists
+	Claim(ttl time.Duration, keys ...string) (bool, error)
+	Release(keys ...string) error
+}
+
+type implementation struct {
+	mutex    sync.Mutex
+	filePath string
+	entries  map[string]entry
+}
+
+// New loads the store persisted at filePath, an empty store is created if the file does not exist yet
+func New(filePath string) (Store, error) {
+	impl := &implementation{
+		filePath: filePath,
+		entries:  map[string]entry{},
+	}
+
+	bytes, err := os.ReadFile(filePath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	if len(bytes) > 0 {
+		err = json.Unmarshal(bytes, &impl.entries)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	impl.prune(time.Now())
+
+	return impl, nil
+}
+
+func (s *implementation) Get(key string) (string, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok || e.expired(time.Now()) {
+		return "", false
+	}
+
+	return e.Value, true
+}
+
+func (s *implementation) Set(key string, value string, ttl time.Duration) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.entries[key] = newEntry(value, ttl)
+
+	return s.persist()
+}
+
+func (s *implementation) Claim(ttl time.Duration, keys ...string) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	for _, key := range keys {
+		e, ok := s.entries[key]
+		if ok && !e.expired(now) {
+			return false, nil
+		}
+	}
+
+	for _, key := range keys {
+		s.entries[key] = newEntry(now.Format(time.RFC3339), ttl)
+	}
+
+	return true, s.persist()
+}
+
+func (s *implementation) Release(keys ...string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, key := range keys {
+		delete(s.entries, key)
+	}
+
+	return s.persist()
+}
+
+func newEntry(value string, ttl time.Duration) entry {
+	e := entry{
+		Value: value,
+	}
+
+	if ttl > 0 {
+		e.ExpiresAt = time.Now().Add(ttl)
+	}
+
+	return e
+}
+
+func (s *implementation) prune(now time.Time) {
+	for key, e := range s.entries {
+		if e.expired(now) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// persist writes the store to a temporary file first, so a crash never leaves a half written store behind
+func (s *implementation) persist() error {
+	s.prune(time.Now())
+
+	bytes, err := json.Marshal(s.entries)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(path.Dir(s.filePath), 0755)
+	if err != nil {
+		return err
+	}
+
+	temporaryFilePath := s.filePath + ".tmp"
+	err = os.WriteFile(temporaryFilePath, bytes, 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(temporaryFilePath, s.filePath)
+}
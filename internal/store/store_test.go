@@ -0,0 +1,202 @@
+package store
+
+import (
+	"fmt"
+	"path"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T) (Store, string) {
+	t.Helper()
+
+	filePath := path.Join(t.TempDir(), "state", "state.json")
+	s, err := New(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s, filePath
+}
+
+func TestExpiry(t *testing.T) {
+	s, _ := newTestStore(t)
+
+	err := s.Set("short", "value", time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Set("forever", "value", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	if _, ok := s.Get("short"); ok {
+		t.Error("expected the key to be gone once its ttl passed")
+	}
+
+	if _, ok := s.Get("forever"); !ok {
+		t.Error("expected a key without ttl to be kept")
+	}
+
+	// an expired key no longer stands in the way of a claim
+	claimed, err := s.Claim(time.Hour, "short")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !claimed {
+		t.Error("expected an expired key to be claimable")
+	}
+}
+
+func TestClaim(t *testing.T) {
+	s, _ := newTestStore(t)
+
+	err := s.Set("job", "original", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a live key refuses the claim, together with every other key of it
+	claimed, err := s.Claim(time.Hour, "delivery", "job")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if claimed {
+		t.Error("expected a live key to refuse the claim")
+	}
+
+	value, ok := s.Get("job")
+	if !ok || value != "original" {
+		t.Errorf("expected the refused claim to leave the value in place, got %q", value)
+	}
+
+	if _, ok := s.Get("delivery"); ok {
+		t.Error("expected a refused claim to record none of its keys")
+	}
+
+	claimed, err = s.Claim(time.Hour, "delivery", "other-job")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !claimed {
+		t.Error("expected keys that do not exist to be claimed")
+	}
+
+	claimed, err = s.Claim(time.Hour, "delivery")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if claimed {
+		t.Error("expected a key to be claimed only once")
+	}
+
+	err = s.Release("delivery")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claimed, err = s.Claim(time.Hour, "delivery")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !claimed {
+		t.Error("expected a released key to be claimable again")
+	}
+}
+
+func TestPersistence(t *testing.T) {
+	s, filePath := newTestStore(t)
+
+	err := s.Set("kept", "value", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Set("expiring", "value", time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Set("released", "value", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Release("released")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	reopened, err := New(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value, ok := reopened.Get("kept")
+	if !ok || value != "value" {
+		t.Errorf("expected the key to survive a reopen, got %q", value)
+	}
+
+	if _, ok := reopened.Get("expiring"); ok {
+		t.Error("expected the expired key to be pruned on reopen")
+	}
+
+	if _, ok := reopened.Get("released"); ok {
+		t.Error("expected the released key to stay released after a reopen")
+	}
+}
+
+func TestConcurrentSet(t *testing.T) {
+	s, filePath := newTestStore(t)
+
+	const writers = 20
+	const keysPerWriter = 10
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*keysPerWriter)
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := range keysPerWriter {
+				errs <- s.Set(fmt.Sprintf("key-%d-%d", i, j), fmt.Sprint(i*keysPerWriter+j), 0)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reopened, err := New(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range writers {
+		for j := range keysPerWriter {
+			key := fmt.Sprintf("key-%d-%d", i, j)
+			value, ok := reopened.Get(key)
+			if !ok || value != fmt.Sprint(i*keysPerWriter+j) {
+				t.Errorf("expected %s to be persisted, got %q", key, value)
+			}
+		}
+	}
+}